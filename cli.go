@@ -0,0 +1,65 @@
+package main
+
+import (
+	"awesomeProject/internal/app/apiserver"
+	"awesomeProject/internal/app/store"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+const (
+	outputTable = "table"
+	outputJson  = "json"
+)
+
+var (
+	errUnknownOutputFormat  = errors.New("output format must be table or json")
+	errUnknownStore         = errors.New("store must be sql or memory")
+	errMemoryStoreServeOnly = errors.New("administrative commands need the sql store, the memory store only lives in the server")
+)
+
+// openStore connects to the database described by config for administrative commands.
+func openStore(config *apiserver.Config) (store.Store, func(), error) {
+	db, err := apiserver.NewDatabaseConn(config, apiserver.NewLogger(config))
+	if err != nil {
+		return nil, nil, err
+	}
+	s := apiserver.NewStore(config, db)
+	return s, func() {
+		_ = apiserver.CloseStore(s)
+		_ = db.Close()
+	}, nil
+}
+
+// printResult writes value as JSON or as a table with the given header and rows.
+func printResult(value interface{}, header []string, rows [][]string) error {
+	switch outputFormat {
+	case outputJson:
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(value)
+	case outputTable:
+		writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(writer, strings.Join(header, "\t"))
+		for _, row := range rows {
+			fmt.Fprintln(writer, strings.Join(row, "\t"))
+		}
+		return writer.Flush()
+	default:
+		return errUnknownOutputFormat
+	}
+}
+
+func generatePassword() (string, error) {
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
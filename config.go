@@ -0,0 +1,17 @@
+package main
+
+import (
+	"awesomeProject/internal/app/apiserver"
+	"errors"
+	"github.com/BurntSushi/toml"
+	"os"
+)
+
+var errConfigUsage = errors.New("usage: config print")
+
+func runConfig(config *apiserver.Config, args []string) error {
+	if len(args) != 1 || args[0] != "print" {
+		return errConfigUsage
+	}
+	return toml.NewEncoder(os.Stdout).Encode(config.Redacted())
+}
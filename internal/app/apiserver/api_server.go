@@ -1,41 +1,273 @@
 package apiserver
 
 import (
+	"awesomeProject/internal/app/blob"
+	"awesomeProject/internal/app/migrator"
+	"awesomeProject/internal/app/store"
+	"awesomeProject/internal/app/store/cachestore"
+	"awesomeProject/internal/app/store/sqlitestore"
 	"awesomeProject/internal/app/store/sqlstore"
+	"awesomeProject/migrations"
+	"context"
 	"database/sql"
+	"errors"
 	sessions2 "github.com/gorilla/sessions"
-	"log"
+	"github.com/sirupsen/logrus"
+	"io"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
 func Start(config *Config) error {
-	db, err := newDatabaseConn(config.DatabaseUrl, config.DatabaseDriverName)
+	logger := NewLogger(config)
+	db, err := NewDatabaseConn(config, logger)
 	if err != nil {
 		return err
 	}
 	defer func(db *sql.DB) {
 		err := db.Close()
 		if err != nil {
-			log.Println(err)
+			logger.Error(err)
 		}
 	}(db)
+	replicas, err := NewReplicaConns(config)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		for _, replica := range replicas {
+			if err := replica.Close(); err != nil {
+				logger.Error(err)
+			}
+		}
+	}()
 
-	store := sqlstore.NewStore(db)
-	sessions := sessions2.NewCookieStore([]byte(config.SessionKey))
-	server := NewServer(store, sessions)
-	err = http.ListenAndServe(config.BindAddr, server)
-	return err
+	schema, err := NewMigrator(config, db)
+	if err != nil {
+		return err
+	}
+	if config.AutoMigrate {
+		if err := schema.Up(context.Background()); err != nil {
+			return err
+		}
+	}
+
+	appStore := NewStore(config, db, replicas...)
+	defer func() {
+		if err := CloseStore(appStore); err != nil {
+			logger.Error(err)
+		}
+	}()
+	var cachedStore *cachestore.Store
+	// The CLI commands write the sqlite file without telling the server, only postgres notifies it of changes.
+	if config.UserCacheSize > 0 && config.DatabaseDriverName == databaseDriverPostgres {
+		cachedStore = cachestore.NewStore(appStore, config.UserCacheSize, config.UserCacheTtl)
+		appStore = cachedStore
+	}
+	server, err := newConfiguredServer(config, appStore, logger)
+	if err != nil {
+		return err
+	}
+	server.AddHealthCheck("database", db.PingContext)
+	server.AddHealthCheck("migrations", schema.Check)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	startWorkers(ctx, server, config)
+	if cachedStore != nil {
+		listener, err := sqlstore.NewUserChangeListener(config.DatabaseUrl)
+		if err != nil {
+			return err
+		}
+		monitor := server.RegisterWorker("user-cache-listener", 3*sqlstore.ListenerPingInterval)
+		go listener.Run(ctx, cachedStore.CachedUsers(), monitor)
+		defer func() {
+			server.logger.Infof("User cache statistics: %+v", cachedStore.CachedUsers().Stats())
+		}()
+	}
+
+	return serve(server, config)
 }
 
-func newDatabaseConn(url string, driverName string) (*sql.DB, error) {
-	db, err := sql.Open(driverName, url)
+// NewLogger returns a logger at the configured level.
+func NewLogger(config *Config) *logrus.Logger {
+	logger := logrus.New()
+	if level, err := logrus.ParseLevel(config.LogLevel); err == nil {
+		logger.SetLevel(level)
+	}
+	return logger
+}
+
+// newConfiguredServer creates the server of Start and StartInMemory.
+func newConfiguredServer(config *Config, appStore store.Store, logger *logrus.Logger) (*Server, error) {
+	sessions := sessions2.NewCookieStore([]byte(config.SessionKey))
+	sessions.Options = config.SessionOptions()
+	server := NewServer(appStore, sessions)
+	server.logger = logger
+	server.readinessTimeout = config.ReadinessTimeout
+	if config.DatabaseWriteTimeout > 0 {
+		server.blobTimeout = config.DatabaseWriteTimeout
+	}
+	blobs, err := blob.NewFSStore(config.AvatarStorageDir)
 	if err != nil {
 		return nil, err
 	}
+	server.SetBlobStore(blobs)
+	if err := server.ApplyConfig(config); err != nil {
+		return nil, err
+	}
+	return server, nil
+}
+
+// startWorkers starts the background work every server needs whatever its store, until ctx is done.
+func startWorkers(ctx context.Context, server *Server, config *Config) {
+	go watchConfig(ctx, server, config)
+	if config.IdempotencySweepInterval > 0 {
+		go sweepIdempotencyKeys(ctx, server, config.IdempotencySweepInterval)
+	}
+}
+
+// serve runs the HTTP server until SIGINT or SIGTERM and then drains it.
+func serve(server *Server, config *Config) error {
+	httpServer := &http.Server{
+		Addr:    config.BindAddr,
+		Handler: server,
+	}
+
+	serveErrors := make(chan error, 1)
+	go func() {
+		serveErrors <- httpServer.ListenAndServe()
+	}()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	select {
+	case err := <-serveErrors:
+		return err
+	case sig := <-signals:
+		server.logger.Infof("Received %v, shutting down", sig)
+	}
 
-	if err := db.Ping(); err != nil {
+	server.BeginShutdown()
+	time.Sleep(config.ShutdownDelay)
+
+	ctx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+	defer cancel()
+	if err := httpServer.Shutdown(ctx); err != nil {
+		return err
+	}
+	if err := <-serveErrors; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+const (
+	databaseConnectInitialBackoff = 250 * time.Millisecond
+	databaseConnectMaxBackoff     = 5 * time.Second
+)
+
+// NewDatabaseConn opens the pool and pings the database with a backoff until database_connect_timeout.
+func NewDatabaseConn(config *Config, logger logrus.FieldLogger) (*sql.DB, error) {
+	db, err := openPool(config, config.DatabaseUrl)
+	if err != nil {
 		return nil, err
 	}
 
+	deadline := time.Now().Add(config.DatabaseConnectTimeout)
+	backoff := databaseConnectInitialBackoff
+	for {
+		err := db.Ping()
+		if err == nil {
+			return db, nil
+		}
+		if time.Now().Add(backoff).After(deadline) {
+			_ = db.Close()
+			return nil, err
+		}
+		logger.Warnf("Database is not available, retrying in %v: %v", backoff, err)
+		time.Sleep(backoff)
+		backoff *= 2
+		if backoff > databaseConnectMaxBackoff {
+			backoff = databaseConnectMaxBackoff
+		}
+	}
+}
+
+// NewReplicaConns opens the replica pools without pinging them, replicas that are down are skipped.
+func NewReplicaConns(config *Config) ([]*sql.DB, error) {
+	replicas := make([]*sql.DB, 0, len(config.DatabaseReplicaUrls))
+	for _, url := range config.DatabaseReplicaUrls {
+		db, err := openPool(config, url)
+		if err != nil {
+			for _, replica := range replicas {
+				_ = replica.Close()
+			}
+			return nil, err
+		}
+		replicas = append(replicas, db)
+	}
+	return replicas, nil
+}
+
+// NewStore creates the store of the configured database driver. Replicas are used only by postgres.
+func NewStore(config *Config, db *sql.DB, replicas ...*sql.DB) store.Store {
+	if config.DatabaseDriverName == databaseDriverSqlite {
+		return sqlitestore.NewStore(db, sqlitestore.WithTimeouts(config.DatabaseReadTimeout, config.DatabaseWriteTimeout))
+	}
+	return sqlstore.NewStore(db, sqlStoreOptions(config, replicas)...)
+}
+
+// sqlStoreOptions returns the options of the sqlstore described by the config.
+func sqlStoreOptions(config *Config, replicas []*sql.DB) []sqlstore.Option {
+	isolation := map[string]sql.IsolationLevel{
+		isolationReadCommitted:  sql.LevelReadCommitted,
+		isolationRepeatableRead: sql.LevelRepeatableRead,
+		isolationSerializable:   sql.LevelSerializable,
+	}[config.DatabaseTxIsolation]
+
+	return []sqlstore.Option{
+		sqlstore.WithTimeouts(config.DatabaseReadTimeout, config.DatabaseWriteTimeout),
+		sqlstore.WithTxIsolation(isolation, config.DatabaseTxRetries),
+		sqlstore.WithReplicas(replicas...),
+	}
+}
+
+// CloseStore releases what a store of NewStore holds on to, the database pools are closed by their owner.
+func CloseStore(s store.Store) error {
+	if closer, ok := s.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// NewMigrator creates the migrator applying the migrations of the configured database driver.
+func NewMigrator(config *Config, db *sql.DB) (*migrator.Migrator, error) {
+	if config.DatabaseDriverName == databaseDriverSqlite {
+		return migrator.NewWithDialect(db, sqlitestore.Migrations, migrator.SQLite)
+	}
+	return migrator.New(db, migrations.FS)
+}
+
+func openPool(config *Config, url string) (*sql.DB, error) {
+	var db *sql.DB
+	var err error
+	if config.DatabaseDriverName == databaseDriverSqlite {
+		db, err = sqlitestore.Open(url)
+	} else {
+		db, err = sql.Open(config.DatabaseDriverName, url)
+	}
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(config.DatabaseMaxOpenConns)
+	db.SetMaxIdleConns(config.DatabaseMaxIdleConns)
+	db.SetConnMaxLifetime(config.DatabaseConnMaxLifetime)
+	db.SetConnMaxIdleTime(config.DatabaseConnMaxIdleTime)
 	return db, nil
 }
@@ -0,0 +1,324 @@
+package apiserver
+
+import (
+	"awesomeProject/internal/app/blob"
+	"awesomeProject/internal/app/imaging"
+	"awesomeProject/internal/app/model"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	avatarField = "avatar"
+	// avatarFormOverhead leaves room for the multipart boundaries and headers around the image.
+	avatarFormOverhead = 64 << 10
+	avatarCacheControl = "public, max-age=31536000, immutable"
+	// defaultBlobTimeout bounds the avatar writes of servers not given database_write_timeout.
+	defaultBlobTimeout = 10 * time.Second
+)
+
+// avatarContentTypes are the sniffed content types accepted as avatars.
+var avatarContentTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+func avatarKey(userId int, version string, size int) string {
+	return fmt.Sprintf("avatars/%d/%s/%d.jpg", userId, version, size)
+}
+
+// avatarUrl is the path handleAvatarGet serves the avatar image of a size at.
+func avatarUrl(userId int, version string, size int) string {
+	return fmt.Sprintf("/v1/avatars/%d/%s/%d.jpg", userId, version, size)
+}
+
+// UserResponse is a user as sent to clients, with the URLs of its avatar images keyed by size.
+type UserResponse struct {
+	*model.User
+	AvatarUrls map[string]string `json:"avatar_urls,omitempty"`
+}
+
+func newUserResponse(user *model.User) *UserResponse {
+	response := &UserResponse{User: user}
+	if user.AvatarVersion != "" {
+		response.AvatarUrls = make(map[string]string, len(model.AvatarSizes))
+		for _, size := range model.AvatarSizes {
+			response.AvatarUrls[strconv.Itoa(size)] = avatarUrl(user.Id, user.AvatarVersion, size)
+		}
+	}
+	return response
+}
+
+// readAvatar streams the multipart form and keeps only the avatar field in memory.
+func (s *Server) readAvatar(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/form-data" {
+		return nil, ErrUnsupportedMediaType
+	}
+	maxSize := s.runtime.Load().avatarMaxSize
+	r.Body = http.MaxBytesReader(w, r.Body, maxSize+avatarFormOverhead)
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, ErrMalformedBody.Wrap(err)
+	}
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return nil, ErrAvatarRequired
+		}
+		if err != nil {
+			return nil, bodyError(err)
+		}
+		if part.FormName() != avatarField {
+			continue
+		}
+		data, err := io.ReadAll(io.LimitReader(part, maxSize+1))
+		if err != nil {
+			return nil, bodyError(err)
+		}
+		if int64(len(data)) > maxSize {
+			return nil, ErrBodyTooLarge
+		}
+		if len(data) == 0 {
+			return nil, ErrAvatarRequired
+		}
+		return data, nil
+	}
+}
+
+// bodyError maps errors reading a multipart body to the problem sent to the client.
+func bodyError(err error) error {
+	var maxBytesError *http.MaxBytesError
+	if errors.As(err, &maxBytesError) {
+		return ErrBodyTooLarge
+	}
+	return ErrMalformedBody.Wrap(err)
+}
+
+// putAvatar stores the thumbnails of an avatar version, removing the stored ones if any fails.
+func (s *Server) putAvatar(ctx context.Context, userId int, version string, thumbnails map[int][]byte) error {
+	ctx, cancel := context.WithTimeout(ctx, s.blobTimeout)
+	defer cancel()
+	for _, size := range model.AvatarSizes {
+		if err := s.blobs.Put(ctx, avatarKey(userId, version, size), bytes.NewReader(thumbnails[size])); err != nil {
+			s.deleteAvatar(userId, version)
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteAvatar is best effort, images left behind are only logged.
+func (s *Server) deleteAvatar(userId int, version string) {
+	if version == "" {
+		return
+	}
+	// The request may be over already, the images are deleted anyway.
+	ctx, cancel := context.WithTimeout(context.Background(), s.blobTimeout)
+	defer cancel()
+	for _, size := range model.AvatarSizes {
+		if err := s.blobs.Delete(ctx, avatarKey(userId, version, size)); err != nil {
+			s.logger.Errorf("Deleting avatar %s failed: %v", avatarKey(userId, version, size), err)
+		}
+	}
+}
+
+// @Summary UploadAvatar
+// @Tags users
+// @Description Replace your avatar with an image sent as the avatar field of a multipart form.
+// @Description The image is cropped to a square and served as JPEG in a few sizes, listed by avatar_urls.
+// @ID users-avatar-upload
+// @Accept mpfd
+// @Produce json
+// @Param avatar formData file true "JPEG, PNG, GIF or WebP image"
+// @Param If-Match header string false "ETag of the user being updated"
+// @Success 200 {object} UserResponse
+// @Header 200 {string} ETag "Version of the updated user"
+// @Failure 400 {object} Problem
+// @Failure 401 {object} Problem
+// @Failure 403 {object} Problem
+// @Failure 412 {object} Problem
+// @Failure 413 {object} Problem
+// @Failure 415 {object} Problem
+// @Failure 422 {object} Problem
+// @Failure 428 {object} Problem
+// @Router /v1/users/me/avatar [put]
+func (s *Server) handleAvatarUpload() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		maybeContextUser := r.Context().Value(userContextKey)
+		if maybeContextUser == nil {
+			s.handleError(w, r, ErrNotAuthenticated)
+			return
+		}
+
+		contextUser := maybeContextUser.(*model.User)
+		if err := s.checkIfMatch(r, contextUser); err != nil {
+			s.handleError(w, r, err)
+			return
+		}
+		data, err := s.readAvatar(w, r)
+		if err != nil {
+			s.handleError(w, r, err)
+			return
+		}
+		// The declared content type is ignored, only the bytes tell what the file is.
+		if !avatarContentTypes[http.DetectContentType(data)] {
+			s.handleError(w, r, ErrUnsupportedImage)
+			return
+		}
+		thumbnails, err := imaging.Thumbnails(data, model.AvatarSizes)
+		if errors.Is(err, imaging.ErrUnsupportedFormat) {
+			s.handleError(w, r, ErrUnsupportedImage)
+			return
+		}
+		if err != nil {
+			s.handleError(w, r, ErrInvalidImage.Wrap(err))
+			return
+		}
+
+		largest := model.AvatarSizes[len(model.AvatarSizes)-1]
+		digest := sha256.Sum256(thumbnails[largest])
+		version := hex.EncodeToString(digest[:8])
+		if version == contextUser.AvatarVersion {
+			w.Header().Set("ETag", entityTag(contextUser))
+			s.respond(w, r, http.StatusOK, newUserResponse(model.Sanitized(contextUser)))
+			return
+		}
+		if err := s.putAvatar(r.Context(), contextUser.Id, version, thumbnails); err != nil {
+			s.handleError(w, r, err)
+			return
+		}
+
+		updated := *contextUser
+		user := &updated
+		user.AvatarVersion = version
+		if err := (*s.store).UserRepository().Update(r.Context(), user); err != nil {
+			s.deleteAvatar(user.Id, version)
+			s.handleError(w, r, err)
+			return
+		}
+		s.deleteAvatar(user.Id, contextUser.AvatarVersion)
+
+		w.Header().Set("ETag", entityTag(user))
+		s.respond(w, r, http.StatusOK, newUserResponse(model.Sanitized(user)))
+	}
+}
+
+// @Summary DeleteAvatar
+// @Tags users
+// @Description Remove your avatar
+// @ID users-avatar-delete
+// @Param If-Match header string false "ETag of the user being updated"
+// @Success 204
+// @Header 204 {string} ETag "Version of the updated user"
+// @Failure 401 {object} Problem
+// @Failure 403 {object} Problem
+// @Failure 412 {object} Problem
+// @Failure 428 {object} Problem
+// @Router /v1/users/me/avatar [delete]
+func (s *Server) handleAvatarDelete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		maybeContextUser := r.Context().Value(userContextKey)
+		if maybeContextUser == nil {
+			s.handleError(w, r, ErrNotAuthenticated)
+			return
+		}
+
+		contextUser := maybeContextUser.(*model.User)
+		if err := s.checkIfMatch(r, contextUser); err != nil {
+			s.handleError(w, r, err)
+			return
+		}
+		if contextUser.AvatarVersion == "" {
+			w.Header().Set("ETag", entityTag(contextUser))
+			s.respond(w, r, http.StatusNoContent, nil)
+			return
+		}
+
+		updated := *contextUser
+		user := &updated
+		user.AvatarVersion = ""
+		if err := (*s.store).UserRepository().Update(r.Context(), user); err != nil {
+			s.handleError(w, r, err)
+			return
+		}
+		s.deleteAvatar(user.Id, contextUser.AvatarVersion)
+
+		w.Header().Set("ETag", entityTag(user))
+		s.respond(w, r, http.StatusNoContent, nil)
+	}
+}
+
+// @Summary GetAvatar
+// @Tags users
+// @Description Get an avatar image from one of the avatar_urls of a user.
+// @Description The URL changes with the image, so responses are cached for good.
+// @ID avatars-get
+// @Produce jpeg
+// @Param id path int true "User id"
+// @Param version path string true "Avatar version"
+// @Param size path int true "Image side in pixels"
+// @Success 200 {file} binary
+// @Success 304
+// @Failure 404 {object} Problem
+// @Router /v1/avatars/{id}/{version}/{size}.jpg [get]
+func (s *Server) handleAvatarGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		userId, err := strconv.Atoi(vars["id"])
+		if err != nil {
+			s.handleError(w, r, ErrNotFound)
+			return
+		}
+		size, err := strconv.Atoi(vars["size"])
+		if err != nil || !isAvatarSize(size) {
+			s.handleError(w, r, ErrNotFound)
+			return
+		}
+
+		image, err := s.blobs.Open(r.Context(), avatarKey(userId, vars["version"], size))
+		if errors.Is(err, blob.ErrNotFound) {
+			s.handleError(w, r, ErrNotFound)
+			return
+		}
+		if err != nil {
+			s.handleError(w, r, err)
+			return
+		}
+		defer image.Close()
+
+		tag := `"` + vars["version"] + "-" + strconv.Itoa(size) + `"`
+		w.Header().Set("Cache-Control", avatarCacheControl)
+		w.Header().Set("ETag", tag)
+		if header := r.Header.Get("If-None-Match"); header != "" && entityTagListContains(header, tag, true) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "image/jpeg")
+		w.WriteHeader(http.StatusOK)
+		if _, err := io.Copy(w, image); err != nil {
+			s.logger.Debugf("Sending avatar failed: %v", err)
+		}
+	}
+}
+
+func isAvatarSize(size int) bool {
+	for _, avatarSize := range model.AvatarSizes {
+		if size == avatarSize {
+			return true
+		}
+	}
+	return false
+}
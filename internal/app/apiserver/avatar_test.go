@@ -0,0 +1,254 @@
+package apiserver_test
+
+import (
+	"awesomeProject/internal/app/apiserver"
+	"awesomeProject/internal/app/blob"
+	"awesomeProject/internal/app/model"
+	"awesomeProject/internal/app/store"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func avatarImage(t *testing.T, width, height int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	var encoded bytes.Buffer
+	require.NoError(t, png.Encode(&encoded, img))
+	return encoded.Bytes()
+}
+
+func avatarForm(t *testing.T, field string, content []byte) (io.Reader, string) {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	require.NoError(t, writer.WriteField("comment", "new avatar"))
+	part, err := writer.CreateFormFile(field, "avatar.png")
+	require.NoError(t, err)
+	_, err = part.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	return &body, writer.FormDataContentType()
+}
+
+// avatarServer returns a server with a signed-in user and the session cookie of its requests.
+func avatarServer(t *testing.T) (*apiserver.Server, store.Store, blob.Store, *model.User, string) {
+	t.Helper()
+	user := store.TestUserHelper(t)()
+	server, s := testSessionServer(t, user)
+	blobs := blob.NewMemoryStore()
+	server.SetBlobStore(blobs)
+	return server, s, blobs, user, testSignedIn(t, user)
+}
+
+func avatarRequest(method string, body io.Reader, contentType string, cookie string) *http.Request {
+	request, _ := http.NewRequest(method, "/v1/users/me/avatar", body)
+	if contentType != "" {
+		request.Header.Set("Content-Type", contentType)
+	}
+	request.Header.Set("Cookie", cookie)
+	request.Header.Set(apiserver.CsrfTokenHeader, testCsrfToken)
+	return request
+}
+
+func TestServer_handleAvatarUpload(t *testing.T) {
+	server, s, blobs, user, cookie := avatarServer(t)
+
+	body, contentType := avatarForm(t, "avatar", avatarImage(t, 300, 200))
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, avatarRequest(http.MethodPut, body, contentType, cookie))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, `"2"`, recorder.Header().Get("ETag"))
+
+	response := struct {
+		AvatarUrls map[string]string `json:"avatar_urls"`
+	}{}
+	require.NoError(t, json.NewDecoder(recorder.Body).Decode(&response))
+	require.Len(t, response.AvatarUrls, len(model.AvatarSizes))
+	updated, err := s.UserRepository().FindById(context.Background(), user.Id)
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("/v1/avatars/%d/%s/64.jpg", user.Id, updated.AvatarVersion), response.AvatarUrls["64"])
+
+	for _, size := range model.AvatarSizes {
+		recorder := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, response.AvatarUrls[fmt.Sprint(size)], nil)
+		server.ServeHTTP(recorder, request)
+		require.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "image/jpeg", recorder.Header().Get("Content-Type"))
+		assert.Equal(t, "public, max-age=31536000, immutable", recorder.Header().Get("Cache-Control"))
+
+		config, format, err := image.DecodeConfig(recorder.Body)
+		require.NoError(t, err)
+		assert.Equal(t, "jpeg", format)
+		assert.Equal(t, size, config.Width)
+		assert.Equal(t, size, config.Height)
+
+		recorder = httptest.NewRecorder()
+		request.Header.Set("If-None-Match", fmt.Sprintf(`"%s-%d"`, updated.AvatarVersion, size))
+		server.ServeHTTP(recorder, request)
+		assert.Equal(t, http.StatusNotModified, recorder.Code)
+	}
+
+	// A new avatar replaces the images of the previous one.
+	previous := updated.AvatarVersion
+	previousUrl := response.AvatarUrls["64"]
+	body, contentType = avatarForm(t, "avatar", avatarImage(t, 100, 100))
+	recorder = httptest.NewRecorder()
+	server.ServeHTTP(recorder, avatarRequest(http.MethodPut, body, contentType, cookie))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	updated, err = s.UserRepository().FindById(context.Background(), user.Id)
+	require.NoError(t, err)
+	assert.NotEqual(t, previous, updated.AvatarVersion)
+	_, err = blobs.Open(context.Background(), fmt.Sprintf("avatars/%d/%s/64.jpg", user.Id, previous))
+	assert.ErrorIs(t, err, blob.ErrNotFound)
+
+	recorder = httptest.NewRecorder()
+	request, _ := http.NewRequest(http.MethodGet, previousUrl, nil)
+	server.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+}
+
+func TestServer_handleAvatarUpload_rejected(t *testing.T) {
+	oversized := make([]byte, 5<<20+1)
+	copy(oversized, avatarImage(t, 10, 10))
+
+	testCases := []struct {
+		key              string
+		field            string
+		content          []byte
+		contentType      string
+		expectedHttpCode int
+	}{
+		{
+			key:              "not an image",
+			field:            "avatar",
+			content:          []byte("<svg xmlns=\"http://www.w3.org/2000/svg\"></svg>"),
+			expectedHttpCode: http.StatusUnsupportedMediaType,
+		},
+		{
+			key:              "damaged image",
+			field:            "avatar",
+			content:          avatarImage(t, 10, 10)[:60],
+			expectedHttpCode: http.StatusUnprocessableEntity,
+		},
+		{
+			key:              "too large",
+			field:            "avatar",
+			content:          oversized,
+			expectedHttpCode: http.StatusRequestEntityTooLarge,
+		},
+		{
+			key:              "missing field",
+			field:            "picture",
+			content:          avatarImage(t, 10, 10),
+			expectedHttpCode: http.StatusBadRequest,
+		},
+		{
+			key:              "not a form",
+			contentType:      "image/png",
+			content:          avatarImage(t, 10, 10),
+			expectedHttpCode: http.StatusUnsupportedMediaType,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.key, func(t *testing.T) {
+			server, s, _, user, cookie := avatarServer(t)
+
+			var body io.Reader = bytes.NewReader(testCase.content)
+			contentType := testCase.contentType
+			if contentType == "" {
+				body, contentType = avatarForm(t, testCase.field, testCase.content)
+			}
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, avatarRequest(http.MethodPut, body, contentType, cookie))
+			assert.Equal(t, testCase.expectedHttpCode, recorder.Code)
+
+			unchanged, err := s.UserRepository().FindById(context.Background(), user.Id)
+			require.NoError(t, err)
+			assert.Empty(t, unchanged.AvatarVersion)
+		})
+	}
+}
+
+func TestServer_handleAvatarDelete(t *testing.T) {
+	server, s, blobs, user, cookie := avatarServer(t)
+
+	body, contentType := avatarForm(t, "avatar", avatarImage(t, 50, 50))
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, avatarRequest(http.MethodPut, body, contentType, cookie))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	uploaded, err := s.UserRepository().FindById(context.Background(), user.Id)
+	require.NoError(t, err)
+
+	recorder = httptest.NewRecorder()
+	server.ServeHTTP(recorder, avatarRequest(http.MethodDelete, nil, "", cookie))
+	assert.Equal(t, http.StatusNoContent, recorder.Code)
+
+	deleted, err := s.UserRepository().FindById(context.Background(), user.Id)
+	require.NoError(t, err)
+	assert.Empty(t, deleted.AvatarVersion)
+	recorder = httptest.NewRecorder()
+	request, _ := http.NewRequest(http.MethodGet, "/v1/users/me", nil)
+	request.Header.Set("Cookie", cookie)
+	server.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.NotContains(t, recorder.Body.String(), "avatar_urls")
+	for _, size := range model.AvatarSizes {
+		_, err = blobs.Open(context.Background(), fmt.Sprintf("avatars/%d/%s/%d.jpg", user.Id, uploaded.AvatarVersion, size))
+		assert.ErrorIs(t, err, blob.ErrNotFound)
+	}
+}
+
+// deadlineBlobStore records whether the writes it gets are bounded.
+type deadlineBlobStore struct {
+	blob.Store
+	unbounded []string
+}
+
+func (s *deadlineBlobStore) Put(ctx context.Context, key string, r io.Reader) error {
+	if _, bounded := ctx.Deadline(); !bounded {
+		s.unbounded = append(s.unbounded, "put "+key)
+	}
+	return s.Store.Put(ctx, key, r)
+}
+
+func (s *deadlineBlobStore) Delete(ctx context.Context, key string) error {
+	if _, bounded := ctx.Deadline(); !bounded {
+		s.unbounded = append(s.unbounded, "delete "+key)
+	}
+	return s.Store.Delete(ctx, key)
+}
+
+func TestServer_handleAvatarUpload_boundedWrites(t *testing.T) {
+	server, _, _, _, cookie := avatarServer(t)
+	blobs := &deadlineBlobStore{Store: blob.NewMemoryStore()}
+	server.SetBlobStore(blobs)
+
+	for _, side := range []int{40, 80} {
+		body, contentType := avatarForm(t, "avatar", avatarImage(t, side, side))
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, avatarRequest(http.MethodPut, body, contentType, cookie))
+		require.Equal(t, http.StatusOK, recorder.Code)
+	}
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, avatarRequest(http.MethodDelete, nil, "", cookie))
+	require.Equal(t, http.StatusNoContent, recorder.Code)
+	assert.Empty(t, blobs.unbounded)
+}
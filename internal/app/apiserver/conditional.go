@@ -0,0 +1,83 @@
+package apiserver
+
+import (
+	"awesomeProject/internal/app/model"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const anyEntityTag = "*"
+
+// entityTag is the strong ETag of a user representation. It changes with every update of the user
+// and with every login, which is part of the representation but leaves the version alone.
+func entityTag(user *model.User) string {
+	if user.LastLoginAt == nil {
+		return versionTag(user)
+	}
+	return `"` + strconv.Itoa(user.Version) + "-" + strconv.FormatInt(user.LastLoginAt.UnixNano(), 10) + `"`
+}
+
+// versionTag is the ETag of a user never logged in, tags of logged in users extend it with the login.
+func versionTag(user *model.User) string {
+	return `"` + strconv.Itoa(user.Version) + `"`
+}
+
+// checkIfMatch refuses requests without If-Match only when require_if_match is enabled.
+func (s *Server) checkIfMatch(r *http.Request, user *model.User) error {
+	header := strings.Join(r.Header.Values("If-Match"), ",")
+	if header == "" {
+		if s.runtime.Load().requireIfMatch {
+			return ErrPreconditionRequired
+		}
+		return nil
+	}
+	// Only the version is compared, a login since the client read the user does not make its change stale.
+	version := versionTag(user)
+	sameVersion := func(candidate string) bool {
+		return candidate == version || strings.HasPrefix(candidate, strings.TrimSuffix(version, `"`)+"-")
+	}
+	if !entityTagListMatches(header, false, sameVersion) {
+		return ErrPreconditionFailed
+	}
+	return nil
+}
+
+// notModified sets the ETag and answers 304 when If-None-Match lists it.
+func (s *Server) notModified(w http.ResponseWriter, r *http.Request, user *model.User) bool {
+	tag := entityTag(user)
+	w.Header().Set("ETag", tag)
+	header := strings.Join(r.Header.Values("If-None-Match"), ",")
+	if header == "" || !entityTagListContains(header, tag, true) {
+		return false
+	}
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// entityTagListContains compares tags weakly or strongly as in RFC 9110.
+func entityTagListContains(list string, tag string, weak bool) bool {
+	return entityTagListMatches(list, weak, func(candidate string) bool {
+		return candidate == tag
+	})
+}
+
+// entityTagListMatches reports whether any tag of list, or the wildcard, is accepted by matches.
+func entityTagListMatches(list string, weak bool, matches func(string) bool) bool {
+	for _, candidate := range strings.Split(list, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == anyEntityTag {
+			return true
+		}
+		if strings.HasPrefix(candidate, "W/") {
+			if !weak {
+				continue
+			}
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if matches(candidate) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,190 @@
+package apiserver_test
+
+import (
+	"awesomeProject/internal/app/apiserver"
+	"awesomeProject/internal/app/store"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestServer_conditionalRequests(t *testing.T) {
+	testCases := []struct {
+		key              string
+		method           string
+		header           string
+		value            string
+		requireIfMatch   bool
+		expectedHttpCode int
+		expectedEtag     string
+	}{
+		{
+			key:              "get",
+			method:           http.MethodGet,
+			expectedHttpCode: http.StatusOK,
+			expectedEtag:     `"1"`,
+		},
+		{
+			key:              "get not modified",
+			method:           http.MethodGet,
+			header:           "If-None-Match",
+			value:            `W/"0", W/"1"`,
+			expectedHttpCode: http.StatusNotModified,
+			expectedEtag:     `"1"`,
+		},
+		{
+			key:              "get modified",
+			method:           http.MethodGet,
+			header:           "If-None-Match",
+			value:            `"0"`,
+			expectedHttpCode: http.StatusOK,
+			expectedEtag:     `"1"`,
+		},
+		{
+			key:              "patch current version",
+			method:           http.MethodPatch,
+			header:           "If-Match",
+			value:            `"1"`,
+			expectedHttpCode: http.StatusOK,
+			expectedEtag:     `"2"`,
+		},
+		{
+			key:              "patch any version",
+			method:           http.MethodPatch,
+			header:           "If-Match",
+			value:            "*",
+			expectedHttpCode: http.StatusOK,
+			expectedEtag:     `"2"`,
+		},
+		{
+			key:              "patch stale version",
+			method:           http.MethodPatch,
+			header:           "If-Match",
+			value:            `"0"`,
+			expectedHttpCode: http.StatusPreconditionFailed,
+		},
+		{
+			key:              "patch weak version",
+			method:           http.MethodPatch,
+			header:           "If-Match",
+			value:            `W/"1"`,
+			expectedHttpCode: http.StatusPreconditionFailed,
+		},
+		{
+			key:              "patch unconditionally",
+			method:           http.MethodPatch,
+			expectedHttpCode: http.StatusOK,
+			expectedEtag:     `"2"`,
+		},
+		{
+			key:              "patch unconditionally when required",
+			method:           http.MethodPatch,
+			requireIfMatch:   true,
+			expectedHttpCode: http.StatusPreconditionRequired,
+		},
+		{
+			key:              "delete stale version",
+			method:           http.MethodDelete,
+			header:           "If-Match",
+			value:            `"0"`,
+			expectedHttpCode: http.StatusPreconditionFailed,
+		},
+		{
+			key:              "delete current version",
+			method:           http.MethodDelete,
+			header:           "If-Match",
+			value:            `"1"`,
+			requireIfMatch:   true,
+			expectedHttpCode: http.StatusOK,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.key, func(t *testing.T) {
+			user := store.TestUserHelper(t)()
+			server, _ := testSessionServer(t, user)
+			config := apiserver.NewConfig()
+			config.RequireIfMatch = testCase.requireIfMatch
+			if err := server.ApplyConfig(config); err != nil {
+				t.Fatal(err)
+			}
+			recorder := httptest.NewRecorder()
+			request, _ := http.NewRequest(testCase.method, "/v1/users/me", strings.NewReader(`{"email": "new@mail.com"}`))
+			request.Header.Set("Content-Type", apiserver.MergePatchContentType)
+			request.Header.Set("Cookie", testSignedIn(t, user))
+			request.Header.Set(apiserver.CsrfTokenHeader, testCsrfToken)
+			if testCase.header != "" {
+				request.Header.Set(testCase.header, testCase.value)
+			}
+			server.ServeHTTP(recorder, request)
+
+			assert.Equal(t, testCase.expectedHttpCode, recorder.Code)
+			assert.Equal(t, testCase.expectedEtag, recorder.Header().Get("ETag"))
+		})
+	}
+}
+
+func TestServer_conditionalRequests_afterLogin(t *testing.T) {
+	user := store.TestUserHelper(t)()
+	server, _ := testSessionServer(t, user)
+	cookie := testSignedIn(t, user)
+
+	recorder := httptest.NewRecorder()
+	request, _ := http.NewRequest(http.MethodGet, "/v1/users/me", nil)
+	request.Header.Set("Cookie", cookie)
+	server.ServeHTTP(recorder, request)
+	etag := recorder.Header().Get("ETag")
+	assert.Equal(t, `"1"`, etag)
+
+	// A login from another device is no edit, the version read before it is still current.
+	recorder = httptest.NewRecorder()
+	request, _ = http.NewRequest(http.MethodPost, "/v1/sessions",
+		strings.NewReader(fmt.Sprintf(`{"email": %q, "password": "super1234pass"}`, user.Email)))
+	request.Header.Set("Content-Type", apiserver.JsonContentType)
+	server.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	request, _ = http.NewRequest(http.MethodPatch, "/v1/users/me", strings.NewReader(`{"display_name": "Andrew"}`))
+	request.Header.Set("Content-Type", apiserver.MergePatchContentType)
+	request.Header.Set("Cookie", cookie)
+	request.Header.Set(apiserver.CsrfTokenHeader, testCsrfToken)
+	// The login is part of the representation, the copy read before it is no longer current.
+	recorder = httptest.NewRecorder()
+	request, _ = http.NewRequest(http.MethodGet, "/v1/users/me", nil)
+	request.Header.Set("Cookie", cookie)
+	request.Header.Set("If-None-Match", etag)
+	server.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	loggedInEtag := recorder.Header().Get("ETag")
+	assert.True(t, strings.HasPrefix(loggedInEtag, `"1-`), loggedInEtag)
+
+	recorder = httptest.NewRecorder()
+	request, _ = http.NewRequest(http.MethodGet, "/v1/users/me", nil)
+	request.Header.Set("Cookie", cookie)
+	request.Header.Set("If-None-Match", loggedInEtag)
+	server.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusNotModified, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	request, _ = http.NewRequest(http.MethodPatch, "/v1/users/me", strings.NewReader(`{"display_name": "Andrew"}`))
+	request.Header.Set("Content-Type", apiserver.MergePatchContentType)
+	request.Header.Set("Cookie", cookie)
+	request.Header.Set(apiserver.CsrfTokenHeader, testCsrfToken)
+	request.Header.Set("If-Match", etag)
+	server.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.True(t, strings.HasPrefix(recorder.Header().Get("ETag"), `"2-`), recorder.Header().Get("ETag"))
+
+	recorder = httptest.NewRecorder()
+	request, _ = http.NewRequest(http.MethodPatch, "/v1/users/me", strings.NewReader(`{"display_name": "Andy"}`))
+	request.Header.Set("Content-Type", apiserver.MergePatchContentType)
+	request.Header.Set("Cookie", cookie)
+	request.Header.Set(apiserver.CsrfTokenHeader, testCsrfToken)
+	request.Header.Set("If-Match", loggedInEtag)
+	server.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusPreconditionFailed, recorder.Code)
+}
@@ -1,21 +1,260 @@
 package apiserver
 
 import (
-	"github.com/BurntSushi/toml"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/gorilla/sessions"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"net/url"
+	"reflect"
+	"regexp"
+	"time"
 )
 
+const (
+	ProfileDev  = "dev"
+	ProfileTest = "test"
+	ProfileProd = "prod"
+
+	minProdSessionKeyLength = 32
+	redactedValue           = "<redacted>"
+
+	sameSiteLax    = "lax"
+	sameSiteStrict = "strict"
+	sameSiteNone   = "none"
+
+	databaseDriverPostgres = "postgres"
+	databaseDriverSqlite   = "sqlite"
+
+	isolationReadCommitted  = "read committed"
+	isolationRepeatableRead = "repeatable read"
+	isolationSerializable   = "serializable"
+)
+
+// knownSessionKeys were committed to the repository and are refused in production.
+var knownSessionKeys = []interface{}{
+	"774F1D42AE59A12CC3A2A936C3518",
+}
+
+var databaseUrlPassword = regexp.MustCompile(`password=('[^']*'|\S*)`)
+
+// Fields tagged secret are redacted and can be read from <key>_file, fields tagged reload are applied on reload.
 type Config struct {
-	BindAddr           string `toml:"bind_addr"`
-	LogLevel           string `toml:"log_level"`
-	DatabaseUrl        string `toml:"database_url"`
-	DatabaseDriverName string `toml:"database_driver_name"`
-	SessionKey         string `toml:"session_key"`
+	Profile                        string        `toml:"profile"`
+	BindAddr                       string        `toml:"bind_addr"`
+	LogLevel                       string        `toml:"log_level" reload:"true"`
+	CorsAllowedOrigins             []string      `toml:"cors_allowed_origins" reload:"true"`
+	CorsAllowedMethods             []string      `toml:"cors_allowed_methods" reload:"true"`
+	CorsAllowedHeaders             []string      `toml:"cors_allowed_headers" reload:"true"`
+	CorsExposedHeaders             []string      `toml:"cors_exposed_headers" reload:"true"`
+	CorsAllowCredentials           bool          `toml:"cors_allow_credentials" reload:"true"`
+	CorsMaxAge                     time.Duration `toml:"cors_max_age" reload:"true"`
+	ConfigWatchInterval            time.Duration `toml:"config_watch_interval"`
+	SecurityHstsMaxAge             time.Duration `toml:"security_hsts_max_age" reload:"true"`
+	SecurityTrustForwardedProto    bool          `toml:"security_trust_forwarded_proto" reload:"true"`
+	SecurityReferrerPolicy         string        `toml:"security_referrer_policy" reload:"true"`
+	SecurityApiCsp                 string        `toml:"security_api_csp" reload:"true"`
+	SecurityDocumentationCsp       string        `toml:"security_documentation_csp" reload:"true"`
+	SecurityAuthorizedCacheControl string        `toml:"security_authorized_cache_control" reload:"true"`
+	RequestMaxBodySize             int64         `toml:"request_max_body_size" reload:"true"`
+	RequireIfMatch                 bool          `toml:"require_if_match" reload:"true"`
+	IdempotencyKeyTtl              time.Duration `toml:"idempotency_key_ttl" reload:"true"`
+	IdempotencySweepInterval       time.Duration `toml:"idempotency_sweep_interval"`
+	DatabaseUrl                    string        `toml:"database_url" secret:"true"`
+	DatabaseUrlFile                string        `toml:"database_url_file"`
+	DatabaseReplicaUrls            []string      `toml:"database_replica_urls"`
+	DatabaseDriverName             string        `toml:"database_driver_name"`
+	DatabaseReadTimeout            time.Duration `toml:"database_read_timeout"`
+	DatabaseWriteTimeout           time.Duration `toml:"database_write_timeout"`
+	DatabaseTxIsolation            string        `toml:"database_tx_isolation"`
+	DatabaseTxRetries              int           `toml:"database_tx_retries"`
+	DatabaseMaxOpenConns           int           `toml:"database_max_open_conns"`
+	DatabaseMaxIdleConns           int           `toml:"database_max_idle_conns"`
+	DatabaseConnMaxLifetime        time.Duration `toml:"database_conn_max_lifetime"`
+	DatabaseConnMaxIdleTime        time.Duration `toml:"database_conn_max_idle_time"`
+	DatabaseConnectTimeout         time.Duration `toml:"database_connect_timeout"`
+	UserCacheSize                  int           `toml:"user_cache_size"`
+	UserCacheTtl                   time.Duration `toml:"user_cache_ttl"`
+	AvatarStorageDir               string        `toml:"avatar_storage_dir"`
+	AvatarMaxSize                  int64         `toml:"avatar_max_size" reload:"true"`
+	SessionKey                     string        `toml:"session_key" secret:"true"`
+	SessionKeyFile                 string        `toml:"session_key_file"`
+	SessionCookieSecure            bool          `toml:"session_cookie_secure"`
+	SessionCookieSameSite          string        `toml:"session_cookie_same_site"`
+	AutoMigrate                    bool          `toml:"auto_migrate"`
+	ReadinessTimeout               time.Duration `toml:"readiness_timeout"`
+	ShutdownDelay                  time.Duration `toml:"shutdown_delay"`
+	ShutdownTimeout                time.Duration `toml:"shutdown_timeout"`
+
+	// paths are the files the config was loaded from, used to reload it.
+	paths               []string
+	sessionKeyGenerated bool
+	// withoutDatabase leaves the database settings unchecked, the memory store does not use them.
+	withoutDatabase bool
+}
+
+func NewConfig() *Config {
+	return &Config{
+		Profile:                ProfileDev,
+		BindAddr:               ":5544",
+		LogLevel:               "Info",
+		CorsAllowedOrigins:     []string{},
+		CorsAllowedMethods:     []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE"},
+		CorsAllowedHeaders:     []string{"Content-Type", "X-Request-ID", "X-CSRF-Token", "If-Match", "If-None-Match", "Idempotency-Key"},
+		CorsExposedHeaders:     []string{"X-Request-ID", "ETag", "Location", "Idempotent-Replayed"},
+		CorsMaxAge:             10 * time.Minute,
+		ConfigWatchInterval:    10 * time.Second,
+		SecurityHstsMaxAge:     180 * 24 * time.Hour,
+		SecurityReferrerPolicy: "no-referrer",
+		SecurityApiCsp:         "default-src 'none'; frame-ancestors 'none'",
+		SecurityDocumentationCsp: "default-src 'self'; script-src 'self' 'nonce-" + cspNoncePlaceholder + "'; " +
+			"style-src 'self' 'unsafe-inline'; img-src 'self' data:; object-src 'none'; base-uri 'none'; frame-ancestors 'none'",
+		SecurityAuthorizedCacheControl: "no-store",
+		RequestMaxBodySize:             1 << 20,
+		IdempotencyKeyTtl:              24 * time.Hour,
+		IdempotencySweepInterval:       time.Hour,
+		DatabaseReplicaUrls:            []string{},
+		DatabaseDriverName:             databaseDriverPostgres,
+		DatabaseReadTimeout:            5 * time.Second,
+		DatabaseWriteTimeout:           10 * time.Second,
+		DatabaseTxIsolation:            isolationReadCommitted,
+		DatabaseTxRetries:              3,
+		DatabaseMaxOpenConns:           25,
+		DatabaseMaxIdleConns:           5,
+		DatabaseConnMaxLifetime:        30 * time.Minute,
+		DatabaseConnMaxIdleTime:        5 * time.Minute,
+		DatabaseConnectTimeout:         30 * time.Second,
+		UserCacheSize:                  10000,
+		UserCacheTtl:                   time.Minute,
+		AvatarStorageDir:               "data/avatars",
+		AvatarMaxSize:                  5 << 20,
+		SessionCookieSameSite:          sameSiteLax,
+		ReadinessTimeout:               defaultReadinessTimeout,
+		ShutdownDelay:                  5 * time.Second,
+		ShutdownTimeout:                15 * time.Second,
+	}
+}
+
+// SessionOptions returns the attributes of the session cookie.
+func (c *Config) SessionOptions() *sessions.Options {
+	sameSite := map[string]http.SameSite{
+		sameSiteLax:    http.SameSiteLaxMode,
+		sameSiteStrict: http.SameSiteStrictMode,
+		sameSiteNone:   http.SameSiteNoneMode,
+	}[c.SessionCookieSameSite]
+
+	return &sessions.Options{
+		Path:     "/",
+		MaxAge:   86400 * 30,
+		HttpOnly: true,
+		Secure:   c.SessionCookieSecure,
+		SameSite: sameSite,
+	}
+}
+
+func (c *Config) Validate() error {
+	sessionKeyRules := []validation.Rule{validation.Required}
+	if c.Profile == ProfileProd {
+		sessionKeyRules = append(sessionKeyRules,
+			validation.Length(minProdSessionKeyLength, 0),
+			validation.NotIn(knownSessionKeys...).Error("must not be a default or publicly known key"),
+		)
+	}
+
+	fields := []*validation.FieldRules{
+		validation.Field(&c.Profile, validation.Required, validation.In(ProfileDev, ProfileTest, ProfileProd)),
+		validation.Field(&c.BindAddr, validation.Required),
+		validation.Field(&c.LogLevel, validation.Required, validation.By(validateLogLevel)),
+		validation.Field(&c.CorsAllowedOrigins, validation.By(validateCorsOrigins), validation.By(c.validateCorsCredentials)),
+		validation.Field(&c.CorsAllowedMethods, validation.Required),
+		validation.Field(&c.CorsMaxAge, validation.Min(time.Duration(0)), validation.Max(10*time.Minute)),
+		validation.Field(&c.SecurityHstsMaxAge, validation.Min(time.Duration(0))),
+		validation.Field(&c.ConfigWatchInterval, validation.Min(time.Duration(0))),
+		validation.Field(&c.RequestMaxBodySize, validation.Required, validation.Min(int64(1))),
+		validation.Field(&c.IdempotencyKeyTtl, validation.Required, validation.Min(time.Second)),
+		validation.Field(&c.IdempotencySweepInterval, validation.Min(time.Duration(0))),
+		validation.Field(&c.UserCacheSize, validation.Min(0)),
+		validation.Field(&c.UserCacheTtl, validation.When(c.UserCacheSize > 0, validation.Required, validation.Min(time.Second))),
+		validation.Field(&c.AvatarStorageDir, validation.Required),
+		validation.Field(&c.AvatarMaxSize, validation.Required, validation.Min(int64(1))),
+		validation.Field(&c.SessionKey, sessionKeyRules...),
+		validation.Field(&c.SessionCookieSameSite, validation.Required,
+			validation.In(sameSiteLax, sameSiteStrict, sameSiteNone), validation.By(c.validateSameSiteNone)),
+		validation.Field(&c.ReadinessTimeout, validation.Min(time.Millisecond)),
+		validation.Field(&c.ShutdownDelay, validation.Min(time.Duration(0))),
+		validation.Field(&c.ShutdownTimeout, validation.Min(time.Millisecond)),
+	}
+	if !c.withoutDatabase {
+		fields = append(fields,
+			validation.Field(&c.DatabaseUrl, validation.Required),
+			validation.Field(&c.DatabaseDriverName, validation.Required, validation.In(databaseDriverPostgres, databaseDriverSqlite)),
+			validation.Field(&c.DatabaseReplicaUrls,
+				validation.When(c.DatabaseDriverName == databaseDriverSqlite, validation.Empty.Error("must be empty with sqlite"))),
+			validation.Field(&c.DatabaseReadTimeout, validation.Min(time.Duration(0))),
+			validation.Field(&c.DatabaseWriteTimeout, validation.Min(time.Duration(0))),
+			validation.Field(&c.DatabaseTxIsolation, validation.Required,
+				validation.In(isolationReadCommitted, isolationRepeatableRead, isolationSerializable)),
+			validation.Field(&c.DatabaseTxRetries, validation.Min(0)),
+			validation.Field(&c.DatabaseMaxOpenConns, validation.Min(0)),
+			validation.Field(&c.DatabaseMaxIdleConns, validation.Min(0)),
+			validation.Field(&c.DatabaseConnMaxLifetime, validation.Min(time.Duration(0))),
+			validation.Field(&c.DatabaseConnMaxIdleTime, validation.Min(time.Duration(0))),
+			validation.Field(&c.DatabaseConnectTimeout, validation.Min(time.Duration(0))),
+		)
+	}
+	return withTomlKeys(c, validation.ValidateStruct(c, fields...))
+}
+
+func (c *Config) validateCorsCredentials(value interface{}) error {
+	if !c.CorsAllowCredentials {
+		return nil
+	}
+	for _, origin := range value.([]string) {
+		if origin == corsAnyOrigin {
+			return ErrCorsWildcardWithCredentials
+		}
+	}
+	return nil
+}
+
+// validateSameSiteNone requires secure cookies for SameSite=None, browsers drop them otherwise.
+func (c *Config) validateSameSiteNone(value interface{}) error {
+	if value.(string) == sameSiteNone && !c.SessionCookieSecure {
+		return ErrSameSiteNoneNotSecure
+	}
+	return nil
+}
+
+func validateLogLevel(value interface{}) error {
+	_, err := logrus.ParseLevel(value.(string))
+	return err
+}
+
+// Redacted returns a copy of the config that is safe to print or log.
+func (c *Config) Redacted() *Config {
+	redacted := *c
+	value := reflect.ValueOf(&redacted).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if field.Tag.Get("secret") != "true" || value.Field(i).String() == "" {
+			continue
+		}
+		value.Field(i).SetString(redactedValue)
+	}
+	if c.DatabaseUrl != "" {
+		redacted.DatabaseUrl = redactDatabaseUrl(c.DatabaseUrl)
+	}
+	redacted.DatabaseReplicaUrls = make([]string, len(c.DatabaseReplicaUrls))
+	for i, replicaUrl := range c.DatabaseReplicaUrls {
+		redacted.DatabaseReplicaUrls[i] = redactDatabaseUrl(replicaUrl)
+	}
+	return &redacted
 }
 
-func NewConfigFromToml(path string) (*Config, error) {
-	config := &Config{}
-	if _, err := toml.DecodeFile(path, config); err != nil {
-		return nil, err
+// redactDatabaseUrl hides only the password.
+func redactDatabaseUrl(databaseUrl string) string {
+	if parsed, err := url.Parse(databaseUrl); err == nil && parsed.Scheme != "" {
+		return parsed.Redacted()
 	}
-	return config, nil
+	return databaseUrlPassword.ReplaceAllString(databaseUrl, "password="+redactedValue)
 }
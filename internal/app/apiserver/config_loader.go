@@ -0,0 +1,251 @@
+package apiserver
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/BurntSushi/toml"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	EnvPrefix  = "APISERVER_"
+	EnvProfile = EnvPrefix + "PROFILE"
+
+	secretFileSuffix = "_file"
+)
+
+// LoadOption changes how LoadConfig checks the config.
+type LoadOption func(c *Config)
+
+// WithoutDatabase loads the config of a server using the memory store, which needs no database settings.
+func WithoutDatabase() LoadOption {
+	return func(c *Config) {
+		c.withoutDatabase = true
+	}
+}
+
+// LoadConfig layers defaults, the file at path, its profile overlay, APISERVER_* variables and <key>_file secrets.
+// The profile defaults to APISERVER_PROFILE and then to prod.
+func LoadConfig(path string, profile string, options ...LoadOption) (*Config, error) {
+	profile = firstNonEmpty(profile, os.Getenv(EnvProfile), ProfileProd)
+
+	config := NewConfig()
+	for _, option := range options {
+		option(config)
+	}
+	if path != "" {
+		if err := decodeConfigFile(path, config); err != nil {
+			return nil, err
+		}
+		config.paths = append(config.paths, path)
+		overlayPath := profileOverlayPath(path, profile)
+		if _, err := os.Stat(overlayPath); err == nil {
+			if err := decodeConfigFile(overlayPath, config); err != nil {
+				return nil, err
+			}
+			config.paths = append(config.paths, overlayPath)
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+	// The profile is chosen from outside only, never by the files it selects.
+	config.Profile = profile
+
+	if err := applyEnvOverrides(config); err != nil {
+		return nil, err
+	}
+	if err := loadSecretFiles(config); err != nil {
+		return nil, err
+	}
+
+	if config.SessionKey == "" && config.Profile != ProfileProd {
+		key, err := randomSessionKey()
+		if err != nil {
+			return nil, err
+		}
+		config.SessionKey = key
+		config.sessionKeyGenerated = true
+		log.Printf("session_key is not set, using a random key: sessions will not survive a restart")
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// Reload loads the config again from the same files and profile.
+func (c *Config) Reload() (*Config, error) {
+	path := ""
+	if len(c.paths) > 0 {
+		path = c.paths[0]
+	}
+	var options []LoadOption
+	if c.withoutDatabase {
+		options = append(options, WithoutDatabase())
+	}
+	config, err := LoadConfig(path, c.Profile, options...)
+	if err != nil {
+		return nil, err
+	}
+	// A generated session key is kept, the running server still signs sessions with it.
+	if config.sessionKeyGenerated && c.sessionKeyGenerated {
+		config.SessionKey = c.SessionKey
+	}
+	return config, nil
+}
+
+func decodeConfigFile(path string, config *Config) error {
+	metadata, err := toml.DecodeFile(path, config)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if undecoded := metadata.Undecoded(); len(undecoded) > 0 {
+		keys := make([]string, 0, len(undecoded))
+		for _, key := range undecoded {
+			keys = append(keys, key.String())
+		}
+		return fmt.Errorf("%s: %w: %s", path, ErrUnknownConfigKey, strings.Join(keys, ", "))
+	}
+	return nil
+}
+
+func profileOverlayPath(path string, profile string) string {
+	extension := filepath.Ext(path)
+	return strings.TrimSuffix(path, extension) + "." + profile + extension
+}
+
+// applyEnvOverrides sets every field whose APISERVER_<TOML KEY> variable is present.
+func applyEnvOverrides(config *Config) error {
+	value := reflect.ValueOf(config).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		key := value.Type().Field(i).Tag.Get("toml")
+		if key == "" || key == "profile" {
+			continue
+		}
+		raw, exist := os.LookupEnv(EnvPrefix + strings.ToUpper(key))
+		if !exist {
+			continue
+		}
+		if err := setFromString(value.Field(i), raw); err != nil {
+			return fmt.Errorf("%s%s: %w", EnvPrefix, strings.ToUpper(key), err)
+		}
+	}
+	return nil
+}
+
+func setFromString(field reflect.Value, raw string) error {
+	if _, isDuration := field.Interface().(time.Duration); isDuration {
+		duration, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(duration))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(raw)
+	case reflect.Bool:
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		field.SetBool(parsed)
+	case reflect.Int, reflect.Int64:
+		parsed, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return err
+		}
+		field.SetInt(parsed)
+	case reflect.Slice:
+		items := make([]string, 0)
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		field.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported config type %v", field.Type())
+	}
+	return nil
+}
+
+// loadSecretFiles reads secret fields from the files named by their <key>_file options.
+func loadSecretFiles(config *Config) error {
+	value := reflect.ValueOf(config).Elem()
+	fileFields := make(map[string]reflect.Value)
+	for i := 0; i < value.NumField(); i++ {
+		fileFields[value.Type().Field(i).Tag.Get("toml")] = value.Field(i)
+	}
+
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if field.Tag.Get("secret") != "true" {
+			continue
+		}
+		key := field.Tag.Get("toml")
+		fileField, exist := fileFields[key+secretFileSuffix]
+		if !exist || fileField.String() == "" {
+			continue
+		}
+		if value.Field(i).String() != "" {
+			return fmt.Errorf("%w: %s and %s%s", ErrConflictingSecret, key, key, secretFileSuffix)
+		}
+		content, err := os.ReadFile(fileField.String())
+		if err != nil {
+			return fmt.Errorf("%s%s: %w", key, secretFileSuffix, err)
+		}
+		value.Field(i).SetString(strings.TrimRight(string(content), "\r\n"))
+	}
+	return nil
+}
+
+func randomSessionKey() (string, error) {
+	buf := make([]byte, minProdSessionKeyLength)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// withTomlKeys reports validation errors by TOML key.
+func withTomlKeys(config *Config, err error) error {
+	fieldErrors, ok := err.(validation.Errors)
+	if !ok {
+		return err
+	}
+
+	configType := reflect.TypeOf(config).Elem()
+	messages := make([]string, 0, len(fieldErrors))
+	for name, fieldErr := range fieldErrors {
+		key := name
+		if field, exist := configType.FieldByName(name); exist {
+			key = field.Tag.Get("toml")
+		}
+		messages = append(messages, fmt.Sprintf("%s: %v", key, fieldErr))
+	}
+	sort.Strings(messages)
+	return fmt.Errorf("%w:\n  %s", ErrInvalidConfig, strings.Join(messages, "\n  "))
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,127 @@
+package apiserver
+
+import (
+	"fmt"
+	"github.com/gorilla/handlers"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const corsAnyOrigin = "*"
+
+// originPattern matches an origin, or all subdomains when the host starts with "*.".
+type originPattern struct {
+	scheme     string
+	host       string
+	port       string
+	subdomains bool
+}
+
+func parseOriginPattern(pattern string) (originPattern, error) {
+	parsed, err := url.Parse(pattern)
+	if err != nil {
+		return originPattern{}, err
+	}
+	if parsed.Scheme == "" || parsed.Host == "" || parsed.User != nil ||
+		(parsed.Path != "" && parsed.Path != "/") || parsed.RawQuery != "" || parsed.Fragment != "" {
+		return originPattern{}, fmt.Errorf("%q is not an origin like https://example.com", pattern)
+	}
+
+	origin := originPattern{
+		scheme: strings.ToLower(parsed.Scheme),
+		host:   strings.ToLower(parsed.Hostname()),
+		port:   parsed.Port(),
+	}
+	if strings.HasPrefix(origin.host, "*.") {
+		origin.host = strings.TrimPrefix(origin.host, "*")
+		origin.subdomains = true
+	}
+	if strings.Contains(origin.host, "*") {
+		return originPattern{}, fmt.Errorf("%q may only use a wildcard as the first host label", pattern)
+	}
+	return origin, nil
+}
+
+func (p originPattern) matches(origin string) bool {
+	parsed, err := url.Parse(origin)
+	if err != nil || strings.ToLower(parsed.Scheme) != p.scheme || parsed.Port() != p.port {
+		return false
+	}
+	host := strings.ToLower(parsed.Hostname())
+	if p.subdomains {
+		return strings.HasSuffix(host, p.host) && len(host) > len(p.host)
+	}
+	return host == p.host
+}
+
+func validateCorsOrigins(value interface{}) error {
+	_, err := parseCorsOrigins(value.([]string))
+	return err
+}
+
+// parseCorsOrigins returns no pattern for a lone wildcard.
+func parseCorsOrigins(origins []string) ([]originPattern, error) {
+	if len(origins) == 1 && origins[0] == corsAnyOrigin {
+		return nil, nil
+	}
+	patterns := make([]originPattern, 0, len(origins))
+	for _, origin := range origins {
+		if origin == corsAnyOrigin {
+			return nil, fmt.Errorf("%q must be the only origin", corsAnyOrigin)
+		}
+		pattern, err := parseOriginPattern(origin)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, nil
+}
+
+func newCorsPolicy(config *Config, patterns []originPattern) func(http.Handler) http.Handler {
+	options := []handlers.CORSOption{
+		handlers.AllowedMethods(config.CorsAllowedMethods),
+		handlers.AllowedHeaders(config.CorsAllowedHeaders),
+		handlers.ExposedHeaders(config.CorsExposedHeaders),
+		handlers.MaxAge(int(config.CorsMaxAge.Seconds())),
+		handlers.OptionStatusCode(http.StatusNoContent),
+	}
+	if config.CorsAllowCredentials {
+		options = append(options, handlers.AllowCredentials())
+	}
+
+	if len(config.CorsAllowedOrigins) == 1 && config.CorsAllowedOrigins[0] == corsAnyOrigin {
+		options = append(options, handlers.AllowedOrigins(config.CorsAllowedOrigins))
+	} else {
+		options = append(options, handlers.AllowedOriginValidator(func(origin string) bool {
+			for _, pattern := range patterns {
+				if pattern.matches(origin) {
+					return true
+				}
+			}
+			return false
+		}))
+	}
+
+	cors := handlers.CORS(options...)
+	return func(next http.Handler) http.Handler {
+		policy := cors(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Responses differ per origin, caches must not share them between origins.
+			w.Header().Add("Vary", "Origin")
+			policy.ServeHTTP(w, r)
+		})
+	}
+}
+
+// trustedOrigins may send state-changing requests, the wildcard is never trusted.
+func trustedOrigins(config *Config) []originPattern {
+	patterns := make([]originPattern, 0, len(config.CorsAllowedOrigins))
+	for _, origin := range config.CorsAllowedOrigins {
+		if pattern, err := parseOriginPattern(origin); err == nil {
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns
+}
@@ -0,0 +1,151 @@
+package apiserver_test
+
+import (
+	"awesomeProject/internal/app/apiserver"
+	"awesomeProject/internal/app/store/teststore"
+	sessions2 "github.com/gorilla/sessions"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestServer_CorsPolicy(t *testing.T) {
+	config := apiserver.NewConfig()
+	config.CorsAllowedOrigins = []string{"https://app.example.com", "https://*.example.org"}
+	config.CorsAllowCredentials = true
+
+	server := apiserver.NewServer(teststore.NewStore(), sessions2.NewCookieStore([]byte("xxx")))
+	if err := server.ApplyConfig(config); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		key                 string
+		method              string
+		origin              string
+		requestMethod       string
+		requestHeaders      string
+		expectedHttpCode    int
+		expectedAllowOrigin string
+	}{
+		{
+			key:                 "exact origin",
+			method:              http.MethodGet,
+			origin:              "https://app.example.com",
+			expectedHttpCode:    http.StatusOK,
+			expectedAllowOrigin: "https://app.example.com",
+		},
+		{
+			key:                 "wildcard subdomain",
+			method:              http.MethodGet,
+			origin:              "https://admin.example.org",
+			expectedHttpCode:    http.StatusOK,
+			expectedAllowOrigin: "https://admin.example.org",
+		},
+		{
+			key:                 "wildcard does not match apex domain",
+			method:              http.MethodGet,
+			origin:              "https://example.org",
+			expectedHttpCode:    http.StatusOK,
+			expectedAllowOrigin: "",
+		},
+		{
+			key:                 "wrong scheme",
+			method:              http.MethodGet,
+			origin:              "http://app.example.com",
+			expectedHttpCode:    http.StatusOK,
+			expectedAllowOrigin: "",
+		},
+		{
+			key:                 "preflight",
+			method:              http.MethodOptions,
+			origin:              "https://app.example.com",
+			requestMethod:       http.MethodDelete,
+			expectedHttpCode:    http.StatusNoContent,
+			expectedAllowOrigin: "https://app.example.com",
+		},
+		{
+			key:                 "preflight with csrf token",
+			method:              http.MethodOptions,
+			origin:              "https://app.example.com",
+			requestMethod:       http.MethodPatch,
+			requestHeaders:      "Content-Type, X-CSRF-Token",
+			expectedHttpCode:    http.StatusNoContent,
+			expectedAllowOrigin: "https://app.example.com",
+		},
+		{
+			key:                 "preflight with preconditions",
+			method:              http.MethodOptions,
+			origin:              "https://app.example.com",
+			requestMethod:       http.MethodPut,
+			requestHeaders:      "If-Match, If-None-Match",
+			expectedHttpCode:    http.StatusNoContent,
+			expectedAllowOrigin: "https://app.example.com",
+		},
+		{
+			key:                 "preflight with idempotency key",
+			method:              http.MethodOptions,
+			origin:              "https://app.example.com",
+			requestMethod:       http.MethodPost,
+			requestHeaders:      "Content-Type, Idempotency-Key",
+			expectedHttpCode:    http.StatusNoContent,
+			expectedAllowOrigin: "https://app.example.com",
+		},
+		{
+			key:              "preflight with unknown header",
+			method:           http.MethodOptions,
+			origin:           "https://app.example.com",
+			requestMethod:    http.MethodPatch,
+			requestHeaders:   "X-Unknown",
+			expectedHttpCode: http.StatusForbidden,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.key, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			request, _ := http.NewRequest(testCase.method, "/healthz", nil)
+			request.Header.Set("Origin", testCase.origin)
+			if testCase.requestMethod != "" {
+				request.Header.Set("Access-Control-Request-Method", testCase.requestMethod)
+			}
+			if testCase.requestHeaders != "" {
+				request.Header.Set("Access-Control-Request-Headers", testCase.requestHeaders)
+			}
+			server.ServeHTTP(recorder, request)
+
+			assert.Equal(t, testCase.expectedHttpCode, recorder.Code)
+			assert.Equal(t, testCase.expectedAllowOrigin, recorder.Header().Get("Access-Control-Allow-Origin"))
+			assert.Contains(t, recorder.Header().Values("Vary"), "Origin")
+			if testCase.expectedAllowOrigin != "" {
+				assert.Equal(t, "true", recorder.Header().Get("Access-Control-Allow-Credentials"))
+			}
+			if testCase.expectedAllowOrigin != "" && testCase.method != http.MethodOptions {
+				assert.Equal(t, "X-Request-Id,Etag,Location,Idempotent-Replayed", recorder.Header().Get("Access-Control-Expose-Headers"))
+			}
+		})
+	}
+}
+
+func TestConfig_ValidateCors(t *testing.T) {
+	config := apiserver.NewConfig()
+	config.DatabaseUrl = "host=db"
+	config.SessionKey = "xxx"
+
+	config.CorsAllowedOrigins = []string{"*"}
+	config.CorsAllowCredentials = true
+	assert.Error(t, config.Validate())
+
+	config.CorsAllowedOrigins = []string{"https://app.*.example.com"}
+	assert.Error(t, config.Validate())
+
+	config.CorsAllowCredentials = false
+	config.CorsAllowedOrigins = []string{"*", "https://app.example.com"}
+	assert.Error(t, config.Validate())
+	config.CorsAllowedOrigins = []string{"*"}
+	assert.NoError(t, config.Validate())
+
+	config.CorsAllowedOrigins = []string{"https://*.example.com", "http://localhost:3000"}
+	assert.NoError(t, config.Validate())
+}
@@ -0,0 +1,139 @@
+package apiserver
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	CsrfTokenHeader     = "X-CSRF-Token"
+	CsrfTokenSessionKey = "csrf_token"
+
+	csrfTokenLength = 32
+)
+
+type authenticationMethod int8
+
+const (
+	// authenticatedBySession marks requests a browser can be tricked into sending cross-site.
+	authenticatedBySession authenticationMethod = iota + 1
+)
+
+type csrfTokenResponse struct {
+	CsrfToken string `json:"csrf_token"`
+}
+
+// VerifyCsrfToken checks the origin and the X-CSRF-Token header of requests authenticated by cookie.
+func (s *Server) VerifyCsrfToken(nextFunc http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, _ := r.Context().Value(authenticationMethodContextKey).(authenticationMethod)
+		if isSafeMethod(r.Method) || method != authenticatedBySession {
+			nextFunc.ServeHTTP(w, r)
+			return
+		}
+
+		if !s.isTrustedRequestOrigin(r) {
+			s.handleError(w, r, ErrCsrfOriginMismatch)
+			return
+		}
+
+		session, err := (*s.sessions).Get(r, SessionName)
+		if err != nil {
+			s.handleError(w, r, err)
+			return
+		}
+		expected, _ := session.Values[CsrfTokenSessionKey].(string)
+		passed := r.Header.Get(CsrfTokenHeader)
+		if expected == "" || subtle.ConstantTimeCompare([]byte(expected), []byte(passed)) != 1 {
+			s.handleError(w, r, ErrCsrfTokenInvalid)
+			return
+		}
+
+		nextFunc.ServeHTTP(w, r)
+	})
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	default:
+		return false
+	}
+}
+
+// isTrustedRequestOrigin checks Origin, or Referer without it, requests with neither rely on the token.
+func (s *Server) isTrustedRequestOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || origin == "null" {
+		referer, err := url.Parse(r.Header.Get("Referer"))
+		if err != nil {
+			return false
+		}
+		if referer.Host == "" {
+			return origin == ""
+		}
+		origin = referer.Scheme + "://" + referer.Host
+	}
+
+	parsed, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(parsed.Host, r.Host) {
+		return true
+	}
+	for _, pattern := range s.runtime.Load().trustedOrigins {
+		if pattern.matches(origin) {
+			return true
+		}
+	}
+	return false
+}
+
+func newCsrfToken() (string, error) {
+	buf := make([]byte, csrfTokenLength)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// @Summary CsrfToken
+// @Tags sessions
+// @Description Get the CSRF token to send in the X-CSRF-Token header of state-changing requests
+// @ID csrf-token
+// @Produce json
+// @Success 200 {object} csrfTokenResponse
+// @Failure 401 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /v1/sessions/csrf-token [get]
+func (s *Server) handleCsrfToken() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		session, err := (*s.sessions).Get(r, SessionName)
+		if err != nil {
+			s.handleError(w, r, err)
+			return
+		}
+
+		token, _ := session.Values[CsrfTokenSessionKey].(string)
+		if token == "" {
+			token, err = newCsrfToken()
+			if err != nil {
+				s.handleError(w, r, err)
+				return
+			}
+			session.Values[CsrfTokenSessionKey] = token
+			if err := (*s.sessions).Save(r, w, session); err != nil {
+				s.handleError(w, r, err)
+				return
+			}
+		}
+
+		s.respond(w, r, http.StatusOK, csrfTokenResponse{CsrfToken: token})
+	}
+}
@@ -0,0 +1,83 @@
+package apiserver_test
+
+import (
+	"awesomeProject/internal/app/apiserver"
+	"awesomeProject/internal/app/store"
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestServer_VerifyCsrfToken(t *testing.T) {
+	user := store.TestUserHelper(t)()
+	server, _ := testSessionServer(t, user)
+
+	recorder := httptest.NewRecorder()
+	request, _ := http.NewRequest(http.MethodGet, "/authorized/csrf-token", nil)
+	request.Header.Set("Cookie", testSessionCookie(t, map[interface{}]interface{}{
+		apiserver.UserIdSessionKey: user.Id,
+	}))
+	server.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	tokenResponse := map[string]string{}
+	if err := json.NewDecoder(recorder.Body).Decode(&tokenResponse); err != nil {
+		t.Fatal(err)
+	}
+	token := tokenResponse["csrf_token"]
+	assert.NotEmpty(t, token)
+	sessionWithToken := recorder.Result().Cookies()[0].Value
+
+	testCases := []struct {
+		key              string
+		token            string
+		origin           string
+		expectedHttpCode int
+	}{
+		{
+			key:              "valid token",
+			token:            token,
+			expectedHttpCode: http.StatusOK,
+		},
+		{
+			key:              "valid token from same origin",
+			token:            token,
+			origin:           "http://api.example.com",
+			expectedHttpCode: http.StatusOK,
+		},
+		{
+			key:              "missing token",
+			expectedHttpCode: http.StatusForbidden,
+		},
+		{
+			key:              "wrong token",
+			token:            token + "x",
+			expectedHttpCode: http.StatusForbidden,
+		},
+		{
+			key:              "foreign origin",
+			token:            token,
+			origin:           "https://evil.example.org",
+			expectedHttpCode: http.StatusForbidden,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.key, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			request, _ := http.NewRequest(http.MethodPut, "http://api.example.com/authorized/logout", nil)
+			request.Header.Set("Cookie", fmt.Sprintf("%s=%s", apiserver.SessionName, sessionWithToken))
+			if testCase.token != "" {
+				request.Header.Set(apiserver.CsrfTokenHeader, testCase.token)
+			}
+			if testCase.origin != "" {
+				request.Header.Set("Origin", testCase.origin)
+			}
+			server.ServeHTTP(recorder, request)
+			assert.Equal(t, testCase.expectedHttpCode, recorder.Code)
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package apiserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+const (
+	JsonContentType = "application/json"
+
+	unknownFieldErrorPrefix = "json: unknown field "
+)
+
+var (
+	errUnknownField = validation.NewError("validation_unknown_field", "is not a known field")
+	errInvalidType  = validation.NewError("validation_invalid_type", "must be a {{.type}}")
+)
+
+// readBody enforces the body size limit and the accepted media types.
+func (s *Server) readBody(w http.ResponseWriter, r *http.Request, mediaTypes ...string) ([]byte, string, error) {
+	mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || !isOneOf(mediaType, mediaTypes) {
+		return nil, "", ErrUnsupportedMediaType.Wrap(fmt.Errorf("content type %q", r.Header.Get("Content-Type")))
+	}
+	if charset, exist := params["charset"]; exist && !strings.EqualFold(charset, "utf-8") {
+		return nil, "", ErrUnsupportedMediaType.Wrap(fmt.Errorf("charset %q", charset))
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, s.runtime.Load().maxBodySize))
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			return nil, "", ErrBodyTooLarge.Wrap(err)
+		}
+		return nil, "", ErrMalformedBody.Wrap(err)
+	}
+	return body, mediaType, nil
+}
+
+// decodeJson rejects unknown fields, mistyped values and trailing data.
+func (s *Server) decodeJson(w http.ResponseWriter, r *http.Request, dst interface{}) error {
+	body, _, err := s.readBody(w, r, JsonContentType)
+	if err != nil {
+		return err
+	}
+	return decodeStrict(body, dst)
+}
+
+func decodeStrict(body []byte, dst interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(dst); err != nil {
+		return toDecodeError(err)
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return ErrMalformedBody.Wrap(errors.New("unexpected data after the JSON value"))
+	}
+	return nil
+}
+
+// toDecodeError reports errors about a particular field as field errors, the way validation errors are.
+func toDecodeError(err error) error {
+	var typeError *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &typeError) && typeError.Field != "":
+		return ErrMalformedBody.Wrap(validation.Errors{
+			typeError.Field: errInvalidType.SetParams(map[string]interface{}{"type": typeError.Type.Kind().String()}),
+		})
+	case strings.HasPrefix(err.Error(), unknownFieldErrorPrefix):
+		field := strings.Trim(strings.TrimPrefix(err.Error(), unknownFieldErrorPrefix), `"`)
+		return ErrUnknownField.Wrap(validation.Errors{field: errUnknownField})
+	default:
+		return ErrMalformedBody.Wrap(err)
+	}
+}
+
+func isOneOf(value string, values []string) bool {
+	for _, candidate := range values {
+		if value == candidate {
+			return true
+		}
+	}
+	return false
+}
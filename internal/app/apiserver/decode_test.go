@@ -0,0 +1,116 @@
+package apiserver_test
+
+import (
+	"awesomeProject/internal/app/apiserver"
+	"awesomeProject/internal/app/store/teststore"
+	"encoding/json"
+	sessions2 "github.com/gorilla/sessions"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestServer_decodeJson(t *testing.T) {
+	testCases := []struct {
+		key              string
+		contentType      string
+		body             string
+		expectedHttpCode int
+		expectedCode     string
+		expectedField    string
+	}{
+		{
+			key:              "valid",
+			contentType:      "application/json; charset=utf-8",
+			body:             `{"email": "abc@mail.com", "password": "1234567890"}`,
+			expectedHttpCode: http.StatusCreated,
+		},
+		{
+			key:              "missing content type",
+			body:             `{"email": "abc@mail.com", "password": "1234567890"}`,
+			expectedHttpCode: http.StatusUnsupportedMediaType,
+			expectedCode:     "unsupported_media_type",
+		},
+		{
+			key:              "form content type",
+			contentType:      "application/x-www-form-urlencoded",
+			body:             "email=abc@mail.com",
+			expectedHttpCode: http.StatusUnsupportedMediaType,
+			expectedCode:     "unsupported_media_type",
+		},
+		{
+			key:              "unsupported charset",
+			contentType:      "application/json; charset=latin1",
+			body:             `{"email": "abc@mail.com", "password": "1234567890"}`,
+			expectedHttpCode: http.StatusUnsupportedMediaType,
+			expectedCode:     "unsupported_media_type",
+		},
+		{
+			key:              "too large",
+			contentType:      apiserver.JsonContentType,
+			body:             `{"email": "abc@mail.com", "password": "` + strings.Repeat("x", 128) + `"}`,
+			expectedHttpCode: http.StatusRequestEntityTooLarge,
+			expectedCode:     "body_too_large",
+		},
+		{
+			key:              "unknown field",
+			contentType:      apiserver.JsonContentType,
+			body:             `{"email": "abc@mail.com", "password": "1234567890", "role": "admin"}`,
+			expectedHttpCode: http.StatusBadRequest,
+			expectedCode:     "unknown_field",
+			expectedField:    "role",
+		},
+		{
+			key:              "wrong type",
+			contentType:      apiserver.JsonContentType,
+			body:             `{"email": 42, "password": "1234567890"}`,
+			expectedHttpCode: http.StatusBadRequest,
+			expectedCode:     "malformed_body",
+			expectedField:    "email",
+		},
+		{
+			key:              "trailing data",
+			contentType:      apiserver.JsonContentType,
+			body:             `{"email": "abc@mail.com", "password": "1234567890"} {}`,
+			expectedHttpCode: http.StatusBadRequest,
+			expectedCode:     "malformed_body",
+		},
+		{
+			key:              "empty body",
+			contentType:      apiserver.JsonContentType,
+			expectedHttpCode: http.StatusBadRequest,
+			expectedCode:     "malformed_body",
+		},
+	}
+	server := apiserver.NewServer(teststore.NewStore(), sessions2.NewCookieStore([]byte("xxx")))
+	config := apiserver.NewConfig()
+	config.RequestMaxBodySize = 100
+	if err := server.ApplyConfig(config); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.key, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			request, _ := http.NewRequest(http.MethodPost, "/sign-up", strings.NewReader(testCase.body))
+			if testCase.contentType != "" {
+				request.Header.Set("Content-Type", testCase.contentType)
+			}
+			server.ServeHTTP(recorder, request)
+			assert.Equal(t, testCase.expectedHttpCode, recorder.Code)
+			if testCase.expectedCode == "" {
+				return
+			}
+
+			var problem apiserver.Problem
+			if assert.NoError(t, json.NewDecoder(recorder.Body).Decode(&problem)) {
+				assert.Equal(t, testCase.expectedCode, problem.Code)
+				if testCase.expectedField != "" && assert.Len(t, problem.Errors, 1) {
+					assert.Equal(t, testCase.expectedField, problem.Errors[0].Field)
+				}
+			}
+		})
+	}
+}
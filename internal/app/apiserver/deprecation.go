@@ -0,0 +1,23 @@
+package apiserver
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// The unversioned routes served before /v1 are kept as aliases until their sunset.
+var (
+	legacyRoutesDeprecatedAt = time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+	legacyRoutesSunset       = time.Date(2027, time.April, 19, 0, 0, 0, 0, time.UTC)
+)
+
+// Deprecated sets the Deprecation and Sunset headers and links the successor route.
+func (s *Server) Deprecated(successor string, nextFunc http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", fmt.Sprintf("@%d", legacyRoutesDeprecatedAt.Unix()))
+		w.Header().Set("Sunset", legacyRoutesSunset.Format(http.TimeFormat))
+		w.Header().Add("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, successor))
+		nextFunc.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,49 @@
+package apiserver_test
+
+import (
+	"awesomeProject/internal/app/apiserver"
+	"awesomeProject/internal/app/store/teststore"
+	sessions2 "github.com/gorilla/sessions"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestServer_Deprecated(t *testing.T) {
+	testCases := []struct {
+		key               string
+		method            string
+		path              string
+		expectedSuccessor string
+	}{
+		{
+			key:               "sign up",
+			method:            http.MethodPost,
+			path:              "/sign-up",
+			expectedSuccessor: "</v1/users>; rel=\"successor-version\"",
+		},
+		{
+			key:               "sign in",
+			method:            http.MethodPost,
+			path:              "/sign-in",
+			expectedSuccessor: "</v1/sessions>; rel=\"successor-version\"",
+		},
+	}
+	server := apiserver.NewServer(teststore.NewStore(), sessions2.NewCookieStore([]byte("xxx")))
+
+	for _, testCase := range testCases {
+		t.Run(testCase.key, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			request, _ := http.NewRequest(testCase.method, testCase.path, strings.NewReader("{}"))
+			request.Header.Set("Content-Type", apiserver.JsonContentType)
+			server.ServeHTTP(recorder, request)
+
+			assert.Regexp(t, `^@\d+$`, recorder.Header().Get("Deprecation"))
+			_, err := http.ParseTime(recorder.Header().Get("Sunset"))
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.expectedSuccessor, recorder.Header().Get("Link"))
+		})
+	}
+}
@@ -1,9 +1,44 @@
 package apiserver
 
-import "errors"
+import (
+	"errors"
+	"net/http"
+)
+
+var (
+	ErrIncorrectEmailOrPassword = NewError(http.StatusUnauthorized, "incorrect_credentials", "incorrect user email or password")
+	ErrNotAuthenticated         = NewError(http.StatusUnauthorized, "not_authenticated", "user is not authenticated")
+	ErrNonEmptyBodyRequired     = NewError(http.StatusBadRequest, "empty_body", "server expected a non empty input body, but got null")
+	ErrMalformedBody            = NewError(http.StatusBadRequest, "malformed_body", "request body is not valid JSON")
+	ErrUnknownField             = NewError(http.StatusBadRequest, "unknown_field", "request body contains unknown fields")
+	ErrBodyTooLarge             = NewError(http.StatusRequestEntityTooLarge, "body_too_large", "request body is too large")
+	ErrUnsupportedMediaType     = NewError(http.StatusUnsupportedMediaType, "unsupported_media_type", "request content type is not supported")
+	ErrUnsupportedImage         = NewError(http.StatusUnsupportedMediaType, "unsupported_image", "image must be a JPEG, PNG, GIF or WebP")
+	ErrInvalidImage             = NewError(http.StatusUnprocessableEntity, "invalid_image", "image is damaged or too large")
+	ErrAvatarRequired           = NewError(http.StatusBadRequest, "avatar_required", "multipart field avatar with the image is required")
+	ErrShuttingDown             = NewError(http.StatusServiceUnavailable, "shutting_down", "server is shutting down")
+	ErrCsrfTokenInvalid         = NewError(http.StatusForbidden, "csrf_token_invalid", "missing or invalid CSRF token")
+	ErrCsrfOriginMismatch       = NewError(http.StatusForbidden, "csrf_origin_mismatch", "request origin is not trusted")
+	ErrNotFound                 = NewError(http.StatusNotFound, "not_found", "requested resource does not exist")
+	ErrMethodNotAllowed         = NewError(http.StatusMethodNotAllowed, "method_not_allowed", "requested resource does not support this method")
+	ErrEmailAlreadyExists       = NewError(http.StatusConflict, "email_already_exists", "user with this email already exists")
+	ErrUsernameAlreadyExists    = NewError(http.StatusConflict, "username_already_exists", "user with this username already exists")
+	ErrPatchConflict            = NewError(http.StatusConflict, "patch_conflict", "patch does not apply to the current state of the resource")
+	ErrPreconditionFailed       = NewError(http.StatusPreconditionFailed, "precondition_failed", "resource was changed since it was read")
+	ErrPreconditionRequired     = NewError(http.StatusPreconditionRequired, "precondition_required", "request must be conditional, send If-Match with the resource ETag")
+	ErrIdempotencyKeyInvalid    = NewError(http.StatusBadRequest, "idempotency_key_invalid", "Idempotency-Key must be at most 255 characters long")
+	ErrIdempotencyKeyInUse      = NewError(http.StatusConflict, "idempotency_key_in_use", "request with this Idempotency-Key is still being processed")
+	ErrIdempotencyKeyReused     = NewError(http.StatusUnprocessableEntity, "idempotency_key_reused", "Idempotency-Key was already used with another payload")
+	ErrValidationFailed         = NewError(http.StatusUnprocessableEntity, "validation_failed", "request contains invalid fields")
+	ErrTimeout                  = NewError(http.StatusServiceUnavailable, "timeout", "request could not be completed in time")
+	ErrRequestCanceled          = NewError(StatusClientClosedRequest, "client_closed_request", "request was canceled by the client")
+	ErrInternal                 = NewError(http.StatusInternalServerError, "internal_error", "internal server error")
+)
 
 var (
-	ErrIncorrectEmailOrPassword = errors.New("incorrect user email or password")
-	ErrNotAuthenticated         = errors.New("user is not authenticated")
-	ErrNonEmptyBodyRequired     = errors.New("server expected a non empty input body, but got null")
+	ErrInvalidConfig               = errors.New("invalid configuration")
+	ErrUnknownConfigKey            = errors.New("unknown configuration keys")
+	ErrConflictingSecret           = errors.New("secret is set both inline and from a file")
+	ErrCorsWildcardWithCredentials = errors.New("must list explicit origins when cors_allow_credentials is enabled")
+	ErrSameSiteNoneNotSecure       = errors.New("must be used with session_cookie_secure enabled")
 )
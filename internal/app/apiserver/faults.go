@@ -0,0 +1,108 @@
+package apiserver
+
+import (
+	"fmt"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"math/rand"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// FaultRule slows down or fails the requests it matches.
+type FaultRule struct {
+	// Method matches any method when empty.
+	Method string `json:"method" yaml:"method"`
+	// Path is a path.Match pattern, e.g. /v1/users/*.
+	Path string `json:"path" yaml:"path"`
+	// Latency is a time.ParseDuration string added before the request is handled.
+	Latency string `json:"latency" yaml:"latency"`
+	// ErrorRate is the probability from 0 to 1 of answering with Status instead of handling the request.
+	ErrorRate float64 `json:"error_rate" yaml:"error_rate"`
+	// Status is the status of the injected errors, 503 by default.
+	Status int `json:"status" yaml:"status"`
+
+	latency time.Duration
+}
+
+type faultsFile struct {
+	Faults []FaultRule `json:"faults" yaml:"faults"`
+}
+
+func (r *FaultRule) Validate() error {
+	return validation.ValidateStruct(r,
+		validation.Field(&r.Path, validation.Required, validation.By(func(interface{}) error {
+			_, err := path.Match(r.Path, "/")
+			return err
+		})),
+		validation.Field(&r.Latency, validation.By(func(interface{}) error {
+			if r.Latency == "" {
+				return nil
+			}
+			_, err := time.ParseDuration(r.Latency)
+			return err
+		})),
+		validation.Field(&r.ErrorRate, validation.Min(0.0), validation.Max(1.0)),
+		validation.Field(&r.Status, validation.When(r.Status != 0, validation.Min(400), validation.Max(599))),
+	)
+}
+
+// LoadFaults reads the fault rules listed under "faults" in a JSON or YAML file.
+func LoadFaults(filePath string) ([]FaultRule, error) {
+	var data faultsFile
+	if err := decodeDataFile(filePath, &data); err != nil {
+		return nil, err
+	}
+	for i := range data.Faults {
+		rule := &data.Faults[i]
+		if err := rule.Validate(); err != nil {
+			return nil, fmt.Errorf("fault %d: %w", i+1, err)
+		}
+		rule.Method = strings.ToUpper(rule.Method)
+		rule.latency, _ = time.ParseDuration(rule.Latency)
+		if rule.Status == 0 {
+			rule.Status = http.StatusServiceUnavailable
+		}
+	}
+	return data.Faults, nil
+}
+
+func (r *FaultRule) matches(request *http.Request) bool {
+	if r.Method != "" && r.Method != request.Method {
+		return false
+	}
+	matched, _ := path.Match(r.Path, request.URL.Path)
+	return matched
+}
+
+// InjectFaults applies the first rule matching each request, before serving starts. Rules must come from LoadFaults.
+func (s *Server) InjectFaults(rules []FaultRule) {
+	s.faults = rules
+}
+
+// InjectedFaults delays or fails requests as told by the rules of InjectFaults, unmatched routes included.
+func (s *Server) InjectedFaults(nextFunc http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := range s.faults {
+			rule := &s.faults[i]
+			if !rule.matches(r) {
+				continue
+			}
+			if rule.latency > 0 {
+				select {
+				case <-time.After(rule.latency):
+				case <-r.Context().Done():
+					s.handleError(w, r, r.Context().Err())
+					return
+				}
+			}
+			if rule.ErrorRate > 0 && rand.Float64() < rule.ErrorRate {
+				s.handleError(w, r, NewError(rule.Status, "injected_fault", "failure injected for "+rule.Path))
+				return
+			}
+			break
+		}
+		nextFunc.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,207 @@
+package apiserver
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	healthStatusOk          = "ok"
+	healthStatusUnavailable = "unavailable"
+
+	defaultReadinessTimeout = 2 * time.Second
+)
+
+// HealthCheck reports whether a dependency is usable before ctx expires.
+type HealthCheck func(ctx context.Context) error
+
+type healthReport struct {
+	Status string                       `json:"status"`
+	Checks map[string]healthCheckResult `json:"checks,omitempty"`
+}
+
+type healthCheckResult struct {
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration,omitempty"`
+}
+
+type healthRegistry struct {
+	mu     sync.RWMutex
+	checks map[string]HealthCheck
+}
+
+func newHealthRegistry() *healthRegistry {
+	return &healthRegistry{
+		checks: make(map[string]HealthCheck),
+	}
+}
+
+func (h *healthRegistry) add(name string, check HealthCheck) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checks[name] = check
+}
+
+func (h *healthRegistry) run(ctx context.Context, timeout time.Duration) healthReport {
+	h.mu.RLock()
+	names := make([]string, 0, len(h.checks))
+	for name := range h.checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	checks := make([]HealthCheck, len(names))
+	for i, name := range names {
+		checks[i] = h.checks[name]
+	}
+	h.mu.RUnlock()
+
+	results := make([]healthCheckResult, len(names))
+	var wg sync.WaitGroup
+	for i := range checks {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = runHealthCheck(ctx, timeout, checks[i])
+		}(i)
+	}
+	wg.Wait()
+
+	report := healthReport{
+		Status: healthStatusOk,
+		Checks: make(map[string]healthCheckResult, len(names)),
+	}
+	for i, name := range names {
+		report.Checks[name] = results[i]
+		if results[i].Status != healthStatusOk {
+			report.Status = healthStatusUnavailable
+		}
+	}
+	return report
+}
+
+func runHealthCheck(ctx context.Context, timeout time.Duration, check HealthCheck) healthCheckResult {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	startTime := time.Now()
+	errs := make(chan error, 1)
+	go func() {
+		errs <- check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-errs:
+	case <-ctx.Done():
+		err = fmt.Errorf("check did not finish in %v", timeout)
+	}
+
+	result := healthCheckResult{
+		Status:   healthStatusOk,
+		Duration: time.Since(startTime).String(),
+	}
+	if err != nil {
+		result.Status = healthStatusUnavailable
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// WorkerMonitor is healthy while Beat is called at least once per maxSilence.
+type WorkerMonitor struct {
+	maxSilence time.Duration
+
+	mu       sync.Mutex
+	lastBeat time.Time
+	err      error
+}
+
+func (m *WorkerMonitor) Beat() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lastBeat = time.Now()
+	m.err = nil
+}
+
+func (m *WorkerMonitor) Fail(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.err = err
+}
+
+func (m *WorkerMonitor) check(_ context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return m.err
+	}
+	if silence := time.Since(m.lastBeat); silence > m.maxSilence {
+		return fmt.Errorf("no heartbeat for %v", silence.Round(time.Second))
+	}
+	return nil
+}
+
+// AddHealthCheck registers a dependency check reported by the readiness probe.
+func (s *Server) AddHealthCheck(name string, check HealthCheck) {
+	s.health.add(name, check)
+}
+
+// RegisterWorker registers a background worker reported by the readiness probe.
+func (s *Server) RegisterWorker(name string, maxSilence time.Duration) *WorkerMonitor {
+	monitor := &WorkerMonitor{
+		maxSilence: maxSilence,
+		lastBeat:   time.Now(),
+	}
+	s.health.add("worker:"+name, monitor.check)
+	return monitor
+}
+
+// BeginShutdown makes the readiness probe fail so that no new traffic is routed to the server.
+func (s *Server) BeginShutdown() {
+	s.shuttingDown.Store(true)
+}
+
+// @Summary Liveness
+// @Tags health
+// @Description Report that the process is alive
+// @ID health-liveness
+// @Produce json
+// @Success 200
+// @Router /healthz [get]
+func (s *Server) handleLiveness() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.respond(w, r, http.StatusOK, healthReport{Status: healthStatusOk})
+	}
+}
+
+// @Summary Readiness
+// @Tags health
+// @Description Report whether the server and its dependencies are ready to accept traffic
+// @ID health-readiness
+// @Produce json
+// @Success 200
+// @Failure 503
+// @Router /readyz [get]
+func (s *Server) handleReadiness() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report := s.health.run(r.Context(), s.readinessTimeout)
+		if s.shuttingDown.Load() {
+			report.Status = healthStatusUnavailable
+			report.Checks["shutdown"] = healthCheckResult{
+				Status: healthStatusUnavailable,
+				Error:  ErrShuttingDown.Error(),
+			}
+		}
+
+		status := http.StatusOK
+		if report.Status != healthStatusOk {
+			status = http.StatusServiceUnavailable
+		}
+		s.respond(w, r, status, report)
+	}
+}
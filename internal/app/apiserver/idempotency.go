@@ -0,0 +1,179 @@
+package apiserver
+
+import (
+	"awesomeProject/internal/app/model"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	IdempotencyKeyHeader     = "Idempotency-Key"
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+	// idempotencyRetryAfter is the delay in seconds suggested to a retry racing the first request.
+	idempotencyRetryAfter = "1"
+	// idempotencyLease bounds how long a reservation abandoned by a crashed process blocks its key.
+	idempotencyLease = time.Minute
+)
+
+// replayedHeaders are the response headers recorded with the body and sent again on replay.
+var replayedHeaders = []string{"Content-Type", "Location", "ETag"}
+
+// Idempotent records the first response to a POST with an Idempotency-Key and replays it to retries.
+func (s *Server) Idempotent(nextFunc http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if key == "" {
+			nextFunc.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			s.handleError(w, r, ErrIdempotencyKeyInvalid)
+			return
+		}
+
+		settings := s.runtime.Load()
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, settings.maxBodySize))
+		if err != nil {
+			var maxBytesError *http.MaxBytesError
+			if errors.As(err, &maxBytesError) {
+				s.handleError(w, r, ErrBodyTooLarge.Wrap(err))
+				return
+			}
+			s.handleError(w, r, ErrMalformedBody.Wrap(err))
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		lease := idempotencyLease
+		if settings.idempotencyKeyTtl < lease {
+			lease = settings.idempotencyKeyTtl
+		}
+		now := time.Now()
+		record := &model.IdempotencyRecord{
+			Scope:       idempotencyScope(r),
+			Key:         key,
+			RequestHash: requestHash(r, body),
+			CreatedAt:   now,
+			ExpiresAt:   now.Add(lease),
+		}
+		repository := (*s.store).IdempotencyRepository()
+		existing, err := repository.Reserve(r.Context(), record)
+		if err != nil {
+			s.handleError(w, r, err)
+			return
+		}
+		if existing != nil {
+			s.replay(w, r, record, existing)
+			return
+		}
+
+		recorded := false
+		defer func() {
+			// Retries depend on the reservation, it is settled even when the client is gone.
+			if !recorded {
+				if err := repository.Release(context.Background(), record); err != nil {
+					s.logger.Errorf("Releasing idempotency key failed: %v", err)
+				}
+			}
+		}()
+
+		response := &bufferedResponseWriter{header: w.Header(), statusCode: http.StatusOK}
+		nextFunc.ServeHTTP(response, r)
+
+		if isFinalStatus(response.statusCode) && r.Context().Err() == nil {
+			record.Status = response.statusCode
+			record.ExpiresAt = time.Now().Add(settings.idempotencyKeyTtl)
+			record.Body = response.body.Bytes()
+			record.Headers = make(map[string]string)
+			for _, name := range replayedHeaders {
+				if value := w.Header().Get(name); value != "" {
+					record.Headers[name] = value
+				}
+			}
+			if err := repository.Complete(context.Background(), record); err != nil {
+				s.logger.Errorf("Recording idempotent response failed: %v", err)
+			} else {
+				recorded = true
+			}
+		}
+		w.WriteHeader(response.statusCode)
+		_, _ = w.Write(response.body.Bytes())
+	})
+}
+
+// isFinalStatus reports whether a retry would get the same response. Server errors, timeouts
+// and requests canceled by the client are not final.
+func isFinalStatus(status int) bool {
+	return status < http.StatusInternalServerError && status != StatusClientClosedRequest
+}
+
+func (s *Server) replay(w http.ResponseWriter, r *http.Request, record *model.IdempotencyRecord, existing *model.IdempotencyRecord) {
+	switch {
+	case existing.RequestHash != record.RequestHash:
+		s.handleError(w, r, ErrIdempotencyKeyReused)
+	case !existing.IsCompleted():
+		w.Header().Set("Retry-After", idempotencyRetryAfter)
+		s.handleError(w, r, ErrIdempotencyKeyInUse)
+	default:
+		for name, value := range existing.Headers {
+			w.Header().Set(name, value)
+		}
+		w.Header().Set(IdempotentReplayedHeader, "true")
+		w.WriteHeader(existing.Status)
+		_, _ = w.Write(existing.Body)
+	}
+}
+
+// idempotencyScope keeps the keys of different routes and clients apart. Anonymous clients are told
+// apart by their address, so one cannot replay the response another got.
+func idempotencyScope(r *http.Request) string {
+	scope := r.Method + " " + r.URL.Path
+	if user, ok := r.Context().Value(userContextKey).(*model.User); ok {
+		return scope + " user:" + strconv.Itoa(user.Id)
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return scope + " addr:" + host
+}
+
+func requestHash(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	_, _ = io.WriteString(hash, r.Header.Get("Content-Type")+"\n")
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// sweepIdempotencyKeys deletes expired idempotency records every interval until ctx is cancelled.
+func sweepIdempotencyKeys(ctx context.Context, server *Server, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	monitor := server.RegisterWorker("idempotency-sweeper", 3*interval)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := (*server.store).IdempotencyRepository().DeleteExpired(ctx, time.Now())
+			if err != nil {
+				server.logger.Errorf("Deleting expired idempotency keys failed: %v", err)
+				monitor.Fail(err)
+				continue
+			}
+			server.logger.Debugf("Deleted %d expired idempotency keys", deleted)
+			monitor.Beat()
+		}
+	}
+}
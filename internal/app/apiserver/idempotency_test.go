@@ -0,0 +1,196 @@
+package apiserver_test
+
+import (
+	"awesomeProject/internal/app/apiserver"
+	"awesomeProject/internal/app/model"
+	"awesomeProject/internal/app/store"
+	"awesomeProject/internal/app/store/teststore"
+	"context"
+	sessions2 "github.com/gorilla/sessions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestServer_Idempotent(t *testing.T) {
+	s := teststore.NewStore()
+	server := apiserver.NewServer(s, sessions2.NewCookieStore([]byte("xxx")))
+
+	signUp := func(key string, body string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodPost, "/v1/users", strings.NewReader(body))
+		request.Header.Set("Content-Type", apiserver.JsonContentType)
+		request.Header.Set(apiserver.IdempotencyKeyHeader, key)
+		server.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	first := signUp("key-1", `{"email": "abc@mail.com", "password": "1234567890"}`)
+	assert.Equal(t, http.StatusCreated, first.Code)
+	assert.Empty(t, first.Header().Get(apiserver.IdempotentReplayedHeader))
+
+	retry := signUp("key-1", `{"email": "abc@mail.com", "password": "1234567890"}`)
+	assert.Equal(t, http.StatusCreated, retry.Code)
+	assert.Equal(t, "true", retry.Header().Get(apiserver.IdempotentReplayedHeader))
+	assert.Equal(t, first.Header().Get("Location"), retry.Header().Get("Location"))
+	assert.Equal(t, first.Header().Get("Content-Type"), retry.Header().Get("Content-Type"))
+	assert.Equal(t, first.Body.String(), retry.Body.String())
+
+	reused := signUp("key-1", `{"email": "other@mail.com", "password": "1234567890"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, reused.Code)
+
+	invalid := signUp("key-2", `{"email": "abc", "password": "1234567890"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, invalid.Code)
+	assert.Equal(t, "true", signUp("key-2", `{"email": "abc", "password": "1234567890"}`).Header().Get(apiserver.IdempotentReplayedHeader))
+
+	tooLong := signUp(strings.Repeat("k", 256), `{"email": "new@mail.com", "password": "1234567890"}`)
+	assert.Equal(t, http.StatusBadRequest, tooLong.Code)
+
+	users, err := s.UserRepository().AllUsers(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, users, 1)
+}
+
+func TestServer_Idempotent_canceledRequest(t *testing.T) {
+	s := teststore.NewStore()
+	server := apiserver.NewServer(s, sessions2.NewCookieStore([]byte("xxx")))
+
+	signUp := func(ctx context.Context) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		request, _ := http.NewRequestWithContext(ctx, http.MethodPost, "/v1/users", strings.NewReader(`{"email": "abc@mail.com", "password": "1234567890"}`))
+		request.Header.Set("Content-Type", apiserver.JsonContentType)
+		request.Header.Set(apiserver.IdempotencyKeyHeader, "key")
+		server.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, apiserver.StatusClientClosedRequest, signUp(ctx).Code)
+
+	retry := signUp(context.Background())
+	assert.Equal(t, http.StatusCreated, retry.Code)
+	assert.Empty(t, retry.Header().Get(apiserver.IdempotentReplayedHeader))
+}
+
+func TestServer_Idempotent_concurrentDuplicates(t *testing.T) {
+	s := teststore.NewStore()
+	server := apiserver.NewServer(s, sessions2.NewCookieStore([]byte("xxx")))
+	s.UserRepository()
+	s.IdempotencyRepository()
+
+	const attempts = 16
+	codes := make(chan *httptest.ResponseRecorder, attempts)
+	var wait sync.WaitGroup
+	for i := 0; i < attempts; i++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			recorder := httptest.NewRecorder()
+			request, _ := http.NewRequest(http.MethodPost, "/v1/users", strings.NewReader(`{"email": "abc@mail.com", "password": "1234567890"}`))
+			request.Header.Set("Content-Type", apiserver.JsonContentType)
+			request.Header.Set(apiserver.IdempotencyKeyHeader, "key")
+			server.ServeHTTP(recorder, request)
+			codes <- recorder
+		}()
+	}
+	wait.Wait()
+	close(codes)
+
+	executed := 0
+	for recorder := range codes {
+		switch {
+		case recorder.Code == http.StatusConflict:
+			assert.NotEmpty(t, recorder.Header().Get("Retry-After"))
+		case recorder.Code == http.StatusCreated && recorder.Header().Get(apiserver.IdempotentReplayedHeader) == "":
+			executed++
+		default:
+			assert.Equal(t, http.StatusCreated, recorder.Code)
+		}
+	}
+	assert.Equal(t, 1, executed)
+
+	users, err := s.UserRepository().AllUsers(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, users, 1)
+}
+
+// crashingStore loses every response, as a process dying between reserving a key and settling it.
+type crashingStore struct {
+	store.Store
+}
+
+func (s *crashingStore) IdempotencyRepository() store.IdempotencyRepository {
+	return &crashingIdempotencyRepository{IdempotencyRepository: s.Store.IdempotencyRepository()}
+}
+
+type crashingIdempotencyRepository struct {
+	store.IdempotencyRepository
+}
+
+func (r *crashingIdempotencyRepository) Complete(context.Context, *model.IdempotencyRecord) error {
+	return nil
+}
+
+func (r *crashingIdempotencyRepository) Release(context.Context, *model.IdempotencyRecord) error {
+	return nil
+}
+
+func TestServer_Idempotent_abandonedReservation(t *testing.T) {
+	s := teststore.NewStore()
+	signUp := func(server *apiserver.Server) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodPost, "/v1/users", strings.NewReader(`{"email": "abc@mail.com", "password": "1234567890"}`))
+		request.Header.Set("Content-Type", apiserver.JsonContentType)
+		request.Header.Set(apiserver.IdempotencyKeyHeader, "key")
+		server.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	crashed := apiserver.NewServer(&crashingStore{Store: s}, sessions2.NewCookieStore([]byte("xxx")))
+	assert.Equal(t, http.StatusCreated, signUp(crashed).Code)
+	// The user the lost response was about is gone again, the retry has to create it anew.
+	user, err := s.UserRepository().FindByEmail(context.Background(), "abc@mail.com")
+	require.NoError(t, err)
+	require.NoError(t, s.UserRepository().Delete(context.Background(), user))
+
+	restarted := apiserver.NewServer(s, sessions2.NewCookieStore([]byte("xxx")))
+	assert.Equal(t, http.StatusConflict, signUp(restarted).Code)
+
+	// The reservation only holds the key for a short lease, not for the whole ttl.
+	deleted, err := s.IdempotencyRepository().DeleteExpired(context.Background(), time.Now().Add(2*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+
+	retry := signUp(restarted)
+	assert.Equal(t, http.StatusCreated, retry.Code)
+	assert.Empty(t, retry.Header().Get(apiserver.IdempotentReplayedHeader))
+}
+
+func TestServer_Idempotent_anonymousClients(t *testing.T) {
+	s := teststore.NewStore()
+	server := apiserver.NewServer(s, sessions2.NewCookieStore([]byte("xxx")))
+
+	signUp := func(remoteAddr string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodPost, "/v1/users", strings.NewReader(`{"email": "abc@mail.com", "password": "1234567890"}`))
+		request.RemoteAddr = remoteAddr
+		request.Header.Set("Content-Type", apiserver.JsonContentType)
+		request.Header.Set(apiserver.IdempotencyKeyHeader, "key")
+		server.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	assert.Equal(t, http.StatusCreated, signUp("192.0.2.1:1234").Code)
+	assert.Equal(t, "true", signUp("192.0.2.1:5678").Header().Get(apiserver.IdempotentReplayedHeader))
+
+	// Another client using the same key runs its own request, which finds the email taken.
+	other := signUp("198.51.100.1:1234")
+	assert.Equal(t, http.StatusConflict, other.Code)
+	assert.Empty(t, other.Header().Get(apiserver.IdempotentReplayedHeader))
+}
@@ -0,0 +1,159 @@
+package apiserver
+
+import (
+	"awesomeProject/internal/app/model"
+	"awesomeProject/internal/app/store"
+	"awesomeProject/internal/app/store/teststore"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// MemoryOptions configure StartInMemory, every file is optional.
+type MemoryOptions struct {
+	// SeedPath is a JSON or YAML file of users created when no snapshot is loaded.
+	SeedPath string
+	// SnapshotPath is loaded on start when it exists and written on shutdown.
+	SnapshotPath string
+	// FaultsPath is a JSON or YAML file of FaultRule.
+	FaultsPath string
+}
+
+// SeedUser is a user created by a seed file, the role and the profile fields get the defaults of model.User.
+type SeedUser struct {
+	Email    string `json:"email" yaml:"email"`
+	Password string `json:"password" yaml:"password"`
+	Role     string `json:"role" yaml:"role"`
+
+	DisplayName string `json:"display_name" yaml:"display_name"`
+	Username    string `json:"username" yaml:"username"`
+	Locale      string `json:"locale" yaml:"locale"`
+	Timezone    string `json:"timezone" yaml:"timezone"`
+}
+
+type seedFile struct {
+	Users []SeedUser `json:"users" yaml:"users"`
+}
+
+// StartInMemory serves from an in-memory store, database settings are ignored.
+func StartInMemory(config *Config, options MemoryOptions) error {
+	memory := teststore.NewStore()
+	loaded, err := loadSnapshot(memory, options.SnapshotPath)
+	if err != nil {
+		return err
+	}
+	if !loaded && options.SeedPath != "" {
+		if err := Seed(context.Background(), memory, options.SeedPath); err != nil {
+			return err
+		}
+	}
+
+	server, err := newConfiguredServer(config, memory, NewLogger(config))
+	if err != nil {
+		return err
+	}
+	if options.FaultsPath != "" {
+		rules, err := LoadFaults(options.FaultsPath)
+		if err != nil {
+			return err
+		}
+		server.InjectFaults(rules)
+	}
+	server.logger.Warn("Serving from an in-memory store, data is lost on shutdown unless a snapshot is configured")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	startWorkers(ctx, server, config)
+
+	if err := serve(server, config); err != nil {
+		return err
+	}
+	return saveSnapshot(memory, options.SnapshotPath)
+}
+
+// Seed creates the users listed under "users" in a JSON or YAML file.
+func Seed(ctx context.Context, s store.Store, seedPath string) error {
+	var data seedFile
+	if err := decodeDataFile(seedPath, &data); err != nil {
+		return err
+	}
+	return s.WithTx(ctx, func(tx store.Store) error {
+		for _, seed := range data.Users {
+			user := &model.User{
+				Email:    seed.Email,
+				Password: &model.Password{Original: seed.Password},
+				Role:     seed.Role,
+
+				DisplayName: seed.DisplayName,
+				Username:    seed.Username,
+				Locale:      seed.Locale,
+				Timezone:    seed.Timezone,
+			}
+			if err := tx.UserRepository().Create(ctx, user); err != nil {
+				return fmt.Errorf("seed user %s: %w", seed.Email, err)
+			}
+		}
+		return nil
+	})
+}
+
+func loadSnapshot(memory *teststore.Store, snapshotPath string) (bool, error) {
+	if snapshotPath == "" {
+		return false, nil
+	}
+	file, err := os.Open(snapshotPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+	if err := memory.Load(file); err != nil {
+		return false, fmt.Errorf("load snapshot %s: %w", snapshotPath, err)
+	}
+	return true, nil
+}
+
+// saveSnapshot writes to a temporary file first, so that a failed write keeps the previous snapshot.
+func saveSnapshot(memory *teststore.Store, snapshotPath string) error {
+	if snapshotPath == "" {
+		return nil
+	}
+	file, err := os.CreateTemp(filepath.Dir(snapshotPath), filepath.Base(snapshotPath)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	if err := memory.Save(file); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), snapshotPath)
+}
+
+// decodeDataFile decodes a YAML file when its extension is .yaml or .yml and a JSON file otherwise.
+func decodeDataFile(filePath string, v interface{}) error {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, v)
+	default:
+		err = json.Unmarshal(content, v)
+	}
+	if err != nil {
+		return fmt.Errorf("decode %s: %w", filePath, err)
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package apiserver_test
+
+import (
+	"awesomeProject/internal/app/apiserver"
+	"awesomeProject/internal/app/model"
+	"awesomeProject/internal/app/store/teststore"
+	"context"
+	"encoding/json"
+	sessions2 "github.com/gorilla/sessions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSeed(t *testing.T) {
+	testCases := []struct {
+		key     string
+		name    string
+		content string
+	}{
+		{
+			key:     "yaml",
+			name:    "seed.yaml",
+			content: "users:\n  - email: admin@gmail.com\n    password: super1234pass\n    role: admin\n  - email: basic@gmail.com\n    password: super1234pass\n",
+		},
+		{
+			key:     "json",
+			name:    "seed.json",
+			content: `{"users": [{"email": "admin@gmail.com", "password": "super1234pass", "role": "admin"}, {"email": "basic@gmail.com", "password": "super1234pass"}]}`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.key, func(t *testing.T) {
+			path := writeConfigFile(t, t.TempDir(), testCase.name, testCase.content)
+			s := teststore.NewStore()
+			require.NoError(t, apiserver.Seed(context.Background(), s, path))
+
+			admin, err := s.UserRepository().FindByEmail(context.Background(), "admin@gmail.com")
+			require.NoError(t, err)
+			assert.Equal(t, model.RoleAdmin, admin.Role)
+			assert.True(t, admin.HasSamePassword("super1234pass"))
+			basic, err := s.UserRepository().FindByEmail(context.Background(), "basic@gmail.com")
+			require.NoError(t, err)
+			assert.Equal(t, model.RoleBasic, basic.Role)
+		})
+	}
+}
+
+func TestSeed_invalidUser(t *testing.T) {
+	path := writeConfigFile(t, t.TempDir(), "seed.yaml", "users:\n  - email: admin@gmail.com\n    password: super1234pass\n  - email: invalid\n    password: super1234pass\n")
+	s := teststore.NewStore()
+	assert.Error(t, apiserver.Seed(context.Background(), s, path))
+
+	_, err := s.UserRepository().FindByEmail(context.Background(), "admin@gmail.com")
+	assert.Error(t, err)
+}
+
+func TestLoadFaults_invalid(t *testing.T) {
+	testCases := []struct {
+		key     string
+		content string
+	}{
+		{
+			key:     "missing path",
+			content: "faults:\n  - latency: 1s\n",
+		},
+		{
+			key:     "invalid latency",
+			content: "faults:\n  - path: /v1/users\n    latency: soon\n",
+		},
+		{
+			key:     "error rate above 1",
+			content: "faults:\n  - path: /v1/users\n    error_rate: 2\n",
+		},
+		{
+			key:     "success status",
+			content: "faults:\n  - path: /v1/users\n    error_rate: 1\n    status: 200\n",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.key, func(t *testing.T) {
+			path := writeConfigFile(t, t.TempDir(), "faults.yaml", testCase.content)
+			_, err := apiserver.LoadFaults(path)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestServer_InjectFaults(t *testing.T) {
+	path := writeConfigFile(t, t.TempDir(), "faults.json", `{"faults": [
+		{"method": "get", "path": "/healthz", "latency": "50ms"},
+		{"path": "/v1/*", "error_rate": 1, "status": 502}
+	]}`)
+	rules, err := apiserver.LoadFaults(path)
+	require.NoError(t, err)
+	server := apiserver.NewServer(teststore.NewStore(), sessions2.NewCookieStore([]byte("xxx")))
+	server.InjectFaults(rules)
+
+	recorder := httptest.NewRecorder()
+	request, _ := http.NewRequest(http.MethodGet, "/healthz", nil)
+	start := time.Now()
+	server.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+
+	recorder = httptest.NewRecorder()
+	request, _ = http.NewRequest(http.MethodGet, "/v1/users", nil)
+	server.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusBadGateway, recorder.Code)
+	var problem apiserver.Problem
+	require.NoError(t, json.NewDecoder(recorder.Body).Decode(&problem))
+	assert.Equal(t, "injected_fault", problem.Code)
+
+	// Paths without a route and methods a route does not allow get the faults too.
+	for _, request := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/v1/missing", nil),
+		httptest.NewRequest(http.MethodPut, "/v1/users", nil),
+	} {
+		recorder = httptest.NewRecorder()
+		server.ServeHTTP(recorder, request)
+		assert.Equal(t, http.StatusBadGateway, recorder.Code, request.URL.Path)
+	}
+
+	recorder = httptest.NewRecorder()
+	request, _ = http.NewRequest(http.MethodGet, "/readyz", nil)
+	server.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+}
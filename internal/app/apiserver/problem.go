@@ -0,0 +1,133 @@
+package apiserver
+
+import (
+	"awesomeProject/internal/app/store"
+	"context"
+	"encoding/json"
+	"errors"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"net/http"
+)
+
+const (
+	ProblemContentType = "application/problem+json"
+	problemTypePrefix  = "urn:apiserver:problem:"
+
+	// StatusClientClosedRequest is the non-standard status logged for requests the client gave up on.
+	StatusClientClosedRequest = 499
+)
+
+// Error is an API error with the HTTP status and the stable machine-readable code it is reported with.
+type Error struct {
+	Status int
+	Code   string
+	Detail string
+	cause  error
+}
+
+func NewError(status int, code string, detail string) *Error {
+	return &Error{
+		Status: status,
+		Code:   code,
+		Detail: detail,
+	}
+}
+
+func (e *Error) Error() string {
+	if e.cause != nil {
+		return e.Detail + ": " + e.cause.Error()
+	}
+	return e.Detail
+}
+
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Is reports errors with the same code as equal, so that wrapped copies match their origin.
+func (e *Error) Is(target error) bool {
+	var apiError *Error
+	return errors.As(target, &apiError) && apiError.Code == e.Code
+}
+
+// Wrap returns a copy of the error caused by err. Causes are logged, never sent to clients.
+func (e *Error) Wrap(err error) *Error {
+	wrapped := *e
+	wrapped.cause = err
+	return &wrapped
+}
+
+// Problem is the RFC 7807 body of every error response.
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Code      string `json:"code"`
+	Detail    string `json:"detail,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	RequestId string `json:"request_id,omitempty"`
+	// Errors lists the invalid fields of a request that failed validation.
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// toApiError classifies any error returned to a handler.
+func toApiError(err error) *Error {
+	var apiError *Error
+	var validationErrors validation.Errors
+	switch {
+	case errors.As(err, &apiError):
+		return apiError
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrTimeout.Wrap(err)
+	case errors.Is(err, context.Canceled):
+		return ErrRequestCanceled.Wrap(err)
+	case errors.Is(err, store.ErrRecordNotFound):
+		return ErrNotFound.Wrap(err)
+	case errors.Is(err, store.ErrEmailAlreadyExists):
+		return ErrEmailAlreadyExists.Wrap(err)
+	case errors.Is(err, store.ErrUsernameAlreadyExists):
+		return ErrUsernameAlreadyExists.Wrap(err)
+	case errors.Is(err, store.ErrVersionConflict):
+		return ErrPreconditionFailed.Wrap(err)
+	case errors.As(err, &validationErrors):
+		return ErrValidationFailed.Wrap(err)
+	default:
+		return ErrInternal.Wrap(err)
+	}
+}
+
+func (s *Server) handleError(w http.ResponseWriter, r *http.Request, err error) {
+	apiError := toApiError(err)
+	requestId, _ := r.Context().Value(requestIdContextKey).(string)
+
+	problem := Problem{
+		Type:      problemTypePrefix + apiError.Code,
+		Title:     statusText(apiError.Status),
+		Status:    apiError.Status,
+		Code:      apiError.Code,
+		Detail:    apiError.Detail,
+		Instance:  r.URL.Path,
+		RequestId: requestId,
+	}
+	var validationErrors validation.Errors
+	if errors.As(err, &validationErrors) {
+		problem.Errors = fieldErrors("", validationErrors)
+	}
+	logger := s.logger.WithField("request_id", requestId)
+	if apiError.Status >= http.StatusInternalServerError {
+		logger.Errorf("%s %s failed: %v", r.Method, r.URL.Path, err)
+	} else {
+		logger.Debugf("%s %s rejected: %v", r.Method, r.URL.Path, err)
+	}
+
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(problem.Status)
+	_ = json.NewEncoder(w).Encode(problem)
+}
+
+func statusText(status int) string {
+	if status == StatusClientClosedRequest {
+		return "Client Closed Request"
+	}
+	return http.StatusText(status)
+}
@@ -0,0 +1,144 @@
+package apiserver_test
+
+import (
+	"awesomeProject/internal/app/apiserver"
+	"awesomeProject/internal/app/store/teststore"
+	"context"
+	"encoding/json"
+	sessions2 "github.com/gorilla/sessions"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServer_handleError(t *testing.T) {
+	testCases := []struct {
+		key              string
+		method           string
+		path             string
+		body             string
+		expectedHttpCode int
+		expectedCode     string
+	}{
+		{
+			key:              "malformed body",
+			method:           http.MethodPost,
+			path:             "/sign-up",
+			body:             "{",
+			expectedHttpCode: http.StatusBadRequest,
+			expectedCode:     "malformed_body",
+		},
+		{
+			key:              "validation failed",
+			method:           http.MethodPost,
+			path:             "/sign-up",
+			body:             `{"email": "abc", "password": ""}`,
+			expectedHttpCode: http.StatusUnprocessableEntity,
+			expectedCode:     "validation_failed",
+		},
+		{
+			key:              "not authenticated",
+			method:           http.MethodGet,
+			path:             "/authorized/whoami",
+			expectedHttpCode: http.StatusUnauthorized,
+			expectedCode:     "not_authenticated",
+		},
+	}
+	server := apiserver.NewServer(teststore.NewStore(), sessions2.NewCookieStore([]byte("xxx")))
+
+	for _, testCase := range testCases {
+		t.Run(testCase.key, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			request, _ := http.NewRequest(testCase.method, testCase.path, strings.NewReader(testCase.body))
+			request.Header.Set("Content-Type", apiserver.JsonContentType)
+			server.ServeHTTP(recorder, request)
+
+			assert.Equal(t, testCase.expectedHttpCode, recorder.Code)
+			assert.Equal(t, apiserver.ProblemContentType, recorder.Header().Get("Content-Type"))
+
+			var problem apiserver.Problem
+			if assert.NoError(t, json.NewDecoder(recorder.Body).Decode(&problem)) {
+				assert.Equal(t, testCase.expectedHttpCode, problem.Status)
+				assert.Equal(t, testCase.expectedCode, problem.Code)
+				assert.Equal(t, "urn:apiserver:problem:"+testCase.expectedCode, problem.Type)
+				assert.Equal(t, testCase.path, problem.Instance)
+				assert.Equal(t, recorder.Header().Get("X-Request-ID"), problem.RequestId)
+			}
+		})
+	}
+}
+
+func TestServer_handleError_fieldErrors(t *testing.T) {
+	server := apiserver.NewServer(teststore.NewStore(), sessions2.NewCookieStore([]byte("xxx")))
+
+	recorder := httptest.NewRecorder()
+	request, _ := http.NewRequest(http.MethodPost, "/sign-up", strings.NewReader(`{"email": "abc", "password": "short"}`))
+	request.Header.Set("Content-Type", apiserver.JsonContentType)
+	server.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+
+	var problem apiserver.Problem
+	if assert.NoError(t, json.NewDecoder(recorder.Body).Decode(&problem)) {
+		assert.Equal(t, []apiserver.FieldError{
+			{
+				Field:   "email",
+				Code:    "is_email",
+				Message: "must be a valid email address",
+			},
+			{
+				Field:   "password",
+				Code:    "length_out_of_range",
+				Message: "the length must be between 8 and 36",
+				Params:  map[string]interface{}{"min": float64(8), "max": float64(36)},
+			},
+		}, problem.Errors)
+	}
+}
+
+func TestServer_handleError_contextErrors(t *testing.T) {
+	expired, cancelExpired := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancelExpired()
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	testCases := []struct {
+		key              string
+		ctx              context.Context
+		expectedHttpCode int
+		expectedCode     string
+	}{
+		{
+			key:              "deadline exceeded",
+			ctx:              expired,
+			expectedHttpCode: http.StatusServiceUnavailable,
+			expectedCode:     "timeout",
+		},
+		{
+			key:              "canceled",
+			ctx:              canceled,
+			expectedHttpCode: apiserver.StatusClientClosedRequest,
+			expectedCode:     "client_closed_request",
+		},
+	}
+	server := apiserver.NewServer(teststore.NewStore(), sessions2.NewCookieStore([]byte("xxx")))
+
+	for _, testCase := range testCases {
+		t.Run(testCase.key, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			request, _ := http.NewRequestWithContext(testCase.ctx, http.MethodPost, "/v1/sessions",
+				strings.NewReader(`{"email": "abc@mail.com", "password": "1234567890"}`))
+			request.Header.Set("Content-Type", apiserver.JsonContentType)
+			server.ServeHTTP(recorder, request)
+
+			assert.Equal(t, testCase.expectedHttpCode, recorder.Code)
+			var problem apiserver.Problem
+			if assert.NoError(t, json.NewDecoder(recorder.Body).Decode(&problem)) {
+				assert.Equal(t, testCase.expectedCode, problem.Code)
+				assert.NotEmpty(t, problem.Title)
+			}
+		})
+	}
+}
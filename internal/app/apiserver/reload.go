@@ -0,0 +1,160 @@
+package apiserver
+
+import (
+	"context"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"os"
+	"os/signal"
+	"reflect"
+	"syscall"
+	"time"
+)
+
+// runtimeSettings is swapped as a whole, so a request sees consistent settings.
+type runtimeSettings struct {
+	handler         http.Handler
+	trustedOrigins  []originPattern
+	securityHeaders securityHeaders
+	maxBodySize     int64
+	avatarMaxSize   int64
+	requireIfMatch  bool
+
+	idempotencyKeyTtl time.Duration
+}
+
+// ApplyConfig swaps in the reloadable settings, nothing changes on error.
+func (s *Server) ApplyConfig(config *Config) error {
+	level, err := logrus.ParseLevel(config.LogLevel)
+	if err != nil {
+		return err
+	}
+	origins, err := parseCorsOrigins(config.CorsAllowedOrigins)
+	if err != nil {
+		return err
+	}
+
+	s.logger.SetLevel(level)
+	s.runtime.Store(s.newRuntimeSettings(config, origins))
+	return nil
+}
+
+func (s *Server) newRuntimeSettings(config *Config, origins []originPattern) *runtimeSettings {
+	return &runtimeSettings{
+		handler:         newCorsPolicy(config, origins)(s.router),
+		trustedOrigins:  trustedOrigins(config),
+		securityHeaders: newSecurityHeaders(config),
+		maxBodySize:     config.RequestMaxBodySize,
+		avatarMaxSize:   config.AvatarMaxSize,
+		requireIfMatch:  config.RequireIfMatch,
+
+		idempotencyKeyTtl: config.IdempotencyKeyTtl,
+	}
+}
+
+// watchConfig reloads the config on SIGHUP and when its files change.
+func watchConfig(ctx context.Context, server *Server, config *Config) {
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+	defer signal.Stop(hangups)
+
+	var ticks <-chan time.Time
+	var monitor *WorkerMonitor
+	if config.ConfigWatchInterval > 0 {
+		ticker := time.NewTicker(config.ConfigWatchInterval)
+		defer ticker.Stop()
+		ticks = ticker.C
+		monitor = server.RegisterWorker("config-watcher", 3*config.ConfigWatchInterval)
+	}
+
+	modified := configModTimes(config)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangups:
+			server.logger.Info("Received SIGHUP, reloading config")
+		case <-ticks:
+			monitor.Beat()
+			current := configModTimes(config)
+			if reflect.DeepEqual(current, modified) {
+				continue
+			}
+			modified = current
+			server.logger.Info("Config files changed, reloading config")
+		}
+		config = reloadConfig(server, config)
+	}
+}
+
+func reloadConfig(server *Server, current *Config) *Config {
+	next, err := current.Reload()
+	if err != nil {
+		server.logger.Errorf("Config reload rejected: %v", err)
+		return current
+	}
+	if err := server.ApplyConfig(next); err != nil {
+		server.logger.Errorf("Config reload rejected: %v", err)
+		return current
+	}
+
+	applied, ignored := diffConfig(current, next)
+	if len(applied) == 0 {
+		server.logger.Info("Config reloaded without changes")
+	}
+	for _, change := range applied {
+		server.logger.Infof("Config reloaded: %s", change)
+	}
+	for _, change := range ignored {
+		server.logger.Warnf("Config change requires a restart: %s", change)
+	}
+
+	// Only reloadable fields took effect, the rest keeps describing the running server.
+	effective := *current
+	copyReloadableFields(&effective, next)
+	return &effective
+}
+
+func configModTimes(config *Config) map[string]time.Time {
+	modTimes := make(map[string]time.Time, len(config.paths))
+	for _, path := range config.paths {
+		if info, err := os.Stat(path); err == nil {
+			modTimes[path] = info.ModTime()
+		}
+	}
+	return modTimes
+}
+
+// diffConfig describes changed fields, split into the ones applied by a reload and the ones that are not.
+func diffConfig(current *Config, next *Config) ([]string, []string) {
+	var applied, ignored []string
+	currentValue, nextValue := reflect.ValueOf(current).Elem(), reflect.ValueOf(next).Elem()
+	for i := 0; i < currentValue.NumField(); i++ {
+		field := currentValue.Type().Field(i)
+		key := field.Tag.Get("toml")
+		if key == "" || reflect.DeepEqual(currentValue.Field(i).Interface(), nextValue.Field(i).Interface()) {
+			continue
+		}
+
+		change := fmt.Sprintf("%s changed", key)
+		if field.Tag.Get("secret") != "true" {
+			change = fmt.Sprintf("%s: %v -> %v", key, currentValue.Field(i).Interface(), nextValue.Field(i).Interface())
+		}
+		if field.Tag.Get("reload") == "true" {
+			applied = append(applied, change)
+		} else {
+			ignored = append(ignored, change)
+		}
+	}
+	return applied, ignored
+}
+
+func copyReloadableFields(dst *Config, src *Config) {
+	dstValue, srcValue := reflect.ValueOf(dst).Elem(), reflect.ValueOf(src).Elem()
+	for i := 0; i < dstValue.NumField(); i++ {
+		if dstValue.Type().Field(i).Tag.Get("reload") == "true" {
+			dstValue.Field(i).Set(srcValue.Field(i))
+		}
+	}
+}
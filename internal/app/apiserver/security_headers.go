@@ -0,0 +1,127 @@
+package apiserver
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// cspNoncePlaceholder is replaced in the documentation CSP by the nonce generated for each response.
+const cspNoncePlaceholder = "{{nonce}}"
+
+// securityHeaders are the response headers built from the security_* config options.
+type securityHeaders struct {
+	hstsValue              string
+	trustForwardedProto    bool
+	referrerPolicy         string
+	apiPolicy              string
+	documentationPolicy    string
+	authorizedCacheControl string
+}
+
+func newSecurityHeaders(config *Config) securityHeaders {
+	headers := securityHeaders{
+		trustForwardedProto:    config.SecurityTrustForwardedProto,
+		referrerPolicy:         config.SecurityReferrerPolicy,
+		apiPolicy:              config.SecurityApiCsp,
+		documentationPolicy:    config.SecurityDocumentationCsp,
+		authorizedCacheControl: config.SecurityAuthorizedCacheControl,
+	}
+	if config.SecurityHstsMaxAge > 0 {
+		headers.hstsValue = fmt.Sprintf("max-age=%d; includeSubDomains", int(config.SecurityHstsMaxAge.Seconds()))
+	}
+	return headers
+}
+
+func (h securityHeaders) isTls(r *http.Request) bool {
+	return r.TLS != nil || (h.trustForwardedProto && strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https"))
+}
+
+func (s *Server) SecureHeaders(nextFunc http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers := s.runtime.Load().securityHeaders
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("X-Frame-Options", "DENY")
+		if headers.referrerPolicy != "" {
+			w.Header().Set("Referrer-Policy", headers.referrerPolicy)
+		}
+		if headers.apiPolicy != "" {
+			w.Header().Set("Content-Security-Policy", headers.apiPolicy)
+		}
+		if headers.hstsValue != "" && headers.isTls(r) {
+			w.Header().Set("Strict-Transport-Security", headers.hstsValue)
+		}
+		nextFunc.ServeHTTP(w, r)
+	})
+}
+
+// PreventCaching keeps responses carrying user data out of browser and proxy caches.
+func (s *Server) PreventCaching(nextFunc http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if cacheControl := s.runtime.Load().securityHeaders.authorizedCacheControl; cacheControl != "" {
+			w.Header().Set("Cache-Control", cacheControl)
+			w.Header().Set("Pragma", "no-cache")
+		}
+		nextFunc.ServeHTTP(w, r)
+	})
+}
+
+// SecureDocumentation allows the Swagger UI scripts through a per-response nonce.
+func (s *Server) SecureDocumentation(nextFunc http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		policy := s.runtime.Load().securityHeaders.documentationPolicy
+		if policy == "" {
+			w.Header().Del("Content-Security-Policy")
+			nextFunc.ServeHTTP(w, r)
+			return
+		}
+
+		nonce, err := newCspNonce()
+		if err != nil {
+			s.handleError(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Security-Policy", strings.ReplaceAll(policy, cspNoncePlaceholder, nonce))
+
+		if !strings.HasSuffix(r.URL.Path, ".html") {
+			nextFunc.ServeHTTP(w, r)
+			return
+		}
+
+		page := &bufferedResponseWriter{header: w.Header(), statusCode: http.StatusOK}
+		nextFunc.ServeHTTP(page, r)
+		body := bytes.ReplaceAll(page.body.Bytes(), []byte("<script"), []byte(`<script nonce="`+nonce+`"`))
+		w.WriteHeader(page.statusCode)
+		_, _ = w.Write(body)
+	})
+}
+
+func newCspNonce() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buf), nil
+}
+
+// bufferedResponseWriter collects a response so that it can be rewritten before it is sent.
+type bufferedResponseWriter struct {
+	header     http.Header
+	statusCode int
+	body       bytes.Buffer
+}
+
+func (b *bufferedResponseWriter) Header() http.Header {
+	return b.header
+}
+
+func (b *bufferedResponseWriter) Write(data []byte) (int, error) {
+	return b.body.Write(data)
+}
+
+func (b *bufferedResponseWriter) WriteHeader(statusCode int) {
+	b.statusCode = statusCode
+}
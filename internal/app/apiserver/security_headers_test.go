@@ -0,0 +1,95 @@
+package apiserver_test
+
+import (
+	"awesomeProject/internal/app/apiserver"
+	"awesomeProject/internal/app/store/teststore"
+	"crypto/tls"
+	sessions2 "github.com/gorilla/sessions"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+func TestServer_SecureHeaders(t *testing.T) {
+	server := apiserver.NewServer(teststore.NewStore(), sessions2.NewCookieStore([]byte("xxx")))
+
+	testCases := []struct {
+		key                  string
+		method               string
+		path                 string
+		tls                  bool
+		expectedContentType  string
+		expectedCacheControl string
+		expectedHsts         bool
+	}{
+		{
+			key:                 "public route",
+			path:                "/healthz",
+			expectedContentType: "application/json; charset=utf-8",
+		},
+		{
+			key:                 "public route over tls",
+			path:                "/healthz",
+			tls:                 true,
+			expectedContentType: "application/json; charset=utf-8",
+			expectedHsts:        true,
+		},
+		{
+			key:                  "authorized route",
+			path:                 "/authorized/whoami",
+			expectedContentType:  apiserver.ProblemContentType,
+			expectedCacheControl: "no-store",
+		},
+		{
+			key:                 "unknown path",
+			path:                "/unknown",
+			expectedContentType: apiserver.ProblemContentType,
+		},
+		{
+			key:                 "method not allowed",
+			method:              http.MethodDelete,
+			path:                "/healthz",
+			expectedContentType: apiserver.ProblemContentType,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.key, func(t *testing.T) {
+			method := testCase.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			recorder := httptest.NewRecorder()
+			request, _ := http.NewRequest(method, testCase.path, nil)
+			if testCase.tls {
+				request.TLS = &tls.ConnectionState{}
+			}
+			server.ServeHTTP(recorder, request)
+
+			assert.Equal(t, testCase.expectedContentType, recorder.Header().Get("Content-Type"))
+			assert.Equal(t, "nosniff", recorder.Header().Get("X-Content-Type-Options"))
+			assert.Equal(t, "no-referrer", recorder.Header().Get("Referrer-Policy"))
+			assert.Equal(t, "default-src 'none'; frame-ancestors 'none'", recorder.Header().Get("Content-Security-Policy"))
+			assert.Equal(t, testCase.expectedCacheControl, recorder.Header().Get("Cache-Control"))
+			assert.Equal(t, testCase.expectedHsts, recorder.Header().Get("Strict-Transport-Security") != "")
+		})
+	}
+}
+
+func TestServer_SecureDocumentation(t *testing.T) {
+	server := apiserver.NewServer(teststore.NewStore(), sessions2.NewCookieStore([]byte("xxx")))
+
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, "/documentation/index.html", nil)
+	server.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	policy := recorder.Header().Get("Content-Security-Policy")
+	nonce := regexp.MustCompile(`'nonce-([^']+)'`).FindStringSubmatch(policy)
+	if assert.Len(t, nonce, 2) {
+		assert.Contains(t, recorder.Body.String(), `<script nonce="`+nonce[1]+`"`)
+		assert.NotRegexp(t, `<script>`, recorder.Body.String())
+	}
+}
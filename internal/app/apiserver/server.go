@@ -2,27 +2,32 @@ package apiserver
 
 import (
 	_ "awesomeProject/docs"
+	"awesomeProject/internal/app/blob"
 	"awesomeProject/internal/app/model"
 	"awesomeProject/internal/app/store"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/google/uuid"
-	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
 	"github.com/gorilla/sessions"
 	"github.com/sirupsen/logrus"
 	"github.com/swaggo/http-swagger"
 	"net/http"
+	"strconv"
+	"sync/atomic"
 	"time"
 )
 
 const (
-	SessionName      = "xxx"
-	UserIdSessionKey = "user_id"
+	SessionName       = "xxx"
+	UserIdSessionKey  = "user_id"
+	SessionVersionKey = "session_version"
 
 	userContextKey contextKey = iota
 	requestIdContextKey
+	authenticationMethodContextKey
 )
 
 type contextKey int8
@@ -32,21 +37,51 @@ type SignRequest struct {
 	Password string `json:"password"`
 }
 
+// UpdateRequest changes the fields it contains, profile fields are set to empty values with "".
+type UpdateRequest struct {
+	Email       string  `json:"email"`
+	Password    string  `json:"password"`
+	DisplayName *string `json:"display_name"`
+	Username    *string `json:"username"`
+	Locale      *string `json:"locale"`
+	Timezone    *string `json:"timezone"`
+}
+
+// empty reports whether the request changes nothing.
+func (r *UpdateRequest) empty() bool {
+	return r.Email == "" && r.Password == "" &&
+		r.DisplayName == nil && r.Username == nil && r.Locale == nil && r.Timezone == nil
+}
+
 type Server struct {
 	logger   *logrus.Logger
 	router   *mux.Router
 	store    *store.Store
 	sessions *sessions.Store
+	blobs    blob.Store
+	faults   []FaultRule
+
+	health           *healthRegistry
+	readinessTimeout time.Duration
+	blobTimeout      time.Duration
+	shuttingDown     atomic.Bool
+
+	runtime atomic.Pointer[runtimeSettings]
 }
 
 func NewServer(store store.Store, sessions sessions.Store) *Server {
 	s := &Server{
-		store:    &store,
-		router:   mux.NewRouter(),
-		logger:   logrus.New(),
-		sessions: &sessions,
+		store:            &store,
+		router:           mux.NewRouter(),
+		logger:           logrus.New(),
+		sessions:         &sessions,
+		blobs:            blob.NewMemoryStore(),
+		health:           newHealthRegistry(),
+		readinessTimeout: defaultReadinessTimeout,
+		blobTimeout:      defaultBlobTimeout,
 	}
 	s.configureRouter()
+	s.runtime.Store(s.newRuntimeSettings(NewConfig(), nil))
 
 	return s
 }
@@ -54,21 +89,63 @@ func NewServer(store store.Store, sessions sessions.Store) *Server {
 func (s *Server) configureRouter() {
 	s.router.Use(s.SetRequestId)
 	s.router.Use(s.LogRequest)
-	s.router.Use(handlers.CORS(handlers.AllowedOrigins([]string{"*"})))
-
-	s.router.PathPrefix("/documentation/").Handler(httpSwagger.WrapHandler)
+	s.router.Use(s.SecureHeaders)
+	s.router.Use(s.ReadPrimaryOnWrite)
+	s.router.Use(s.InjectedFaults)
+	s.router.NotFoundHandler = s.unmatched(ErrNotFound)
+	s.router.MethodNotAllowedHandler = s.unmatched(ErrMethodNotAllowed)
+
+	s.router.HandleFunc("/healthz", s.handleLiveness()).Methods("GET")
+	s.router.HandleFunc("/readyz", s.handleReadiness()).Methods("GET")
+
+	s.router.PathPrefix("/documentation/").Handler(s.SecureDocumentation(httpSwagger.WrapHandler))
+	// Avatars are public and cached for good, the routes of /v1 prevent caching.
+	s.router.HandleFunc("/v1/avatars/{id:[0-9]+}/{version:[0-9a-f]+}/{size:[0-9]+}.jpg", s.handleAvatarGet()).Methods("GET")
+
+	v1 := s.router.PathPrefix("/v1").Subrouter()
+	v1.Use(s.PreventCaching)
+	v1.Handle("/users", s.Idempotent(s.handleUserCreate())).Methods("POST")
+	v1.Handle("/users", s.authorized(s.handleUsersGetAll())).Methods("GET")
+	v1.Handle("/users/me", s.authorized(s.handleWhoAmI())).Methods("GET")
+	v1.Handle("/users/me", s.authorized(s.handleUserPatch())).Methods("PATCH")
+	v1.Handle("/users/me", s.authorized(s.handleUserDelete())).Methods("DELETE")
+	v1.Handle("/users/me/avatar", s.authorized(s.handleAvatarUpload())).Methods("PUT")
+	v1.Handle("/users/me/avatar", s.authorized(s.handleAvatarDelete())).Methods("DELETE")
+	v1.Handle("/users/{id:[0-9]+}", s.authorized(s.handleUserGet())).Methods("GET")
+	v1.HandleFunc("/sessions", s.handleSessionCreate()).Methods("POST")
+	v1.Handle("/sessions", s.authorized(s.handleSessionLogout())).Methods("DELETE")
+	v1.Handle("/sessions/csrf-token", s.authorized(s.handleCsrfToken())).Methods("GET")
+
+	s.router.Handle("/sign-up", s.Deprecated("/v1/users", s.Idempotent(s.handleUserCreate()))).Methods("POST")
+	s.router.Handle("/sign-in", s.Deprecated("/v1/sessions", s.PreventCaching(s.handleSessionCreate()))).Methods("POST")
+
+	legacySubRouter := s.router.PathPrefix("/authorized").Subrouter()
+	legacySubRouter.Use(s.PreventCaching)
+	legacySubRouter.Use(s.AuthenticateUser)
+	legacySubRouter.Use(s.VerifyCsrfToken)
+	legacySubRouter.Handle("/csrf-token", s.Deprecated("/v1/sessions/csrf-token", s.handleCsrfToken())).Methods("GET")
+	legacySubRouter.Handle("/whoami", s.Deprecated("/v1/users/me", s.handleWhoAmI())).Methods("GET")
+	legacySubRouter.Handle("/users", s.Deprecated("/v1/users", s.handleUsersGetAll())).Methods("GET")
+	legacySubRouter.Handle("/update", s.Deprecated("/v1/users/me", s.handleUserUpdate())).Methods("POST", "PUT")
+	legacySubRouter.Handle("/delete", s.Deprecated("/v1/users/me", s.handleUserDelete())).Methods("DELETE")
+	legacySubRouter.Handle("/logout", s.Deprecated("/v1/sessions", s.handleSessionLogout())).Methods("PUT")
+}
 
-	s.router.HandleFunc("/sign-up", s.handleUserCreate()).Methods("POST")
-	s.router.HandleFunc("/sign-in", s.handleSessionCreate()).Methods("POST")
+// unmatched applies the middlewares mux skips for requests no route matched.
+func (s *Server) unmatched(err error) http.Handler {
+	return s.SetRequestId(s.LogRequest(s.SecureHeaders(s.InjectedFaults(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.handleError(w, r, err)
+	})))))
+}
 
-	privateSubRouter := s.router.PathPrefix("/authorized").Subrouter()
-	privateSubRouter.Use(s.AuthenticateUser)
-	privateSubRouter.HandleFunc("/whoami", s.handleWhoAmI()).Methods("GET")
-	privateSubRouter.HandleFunc("/users", s.handleUsersGetAll()).Methods("GET")
-	privateSubRouter.HandleFunc("/update", s.handleUserUpdate()).Methods("POST", "PUT")
-	privateSubRouter.HandleFunc("/delete", s.handleUserDelete()).Methods("DELETE")
-	privateSubRouter.HandleFunc("/logout", s.handleSessionLogout()).Methods("PUT")
+// authorized protects a route with the session authentication and the CSRF check.
+func (s *Server) authorized(handler http.Handler) http.Handler {
+	return s.AuthenticateUser(s.VerifyCsrfToken(handler))
+}
 
+// SetBlobStore replaces the store of the uploaded files, kept in memory by default.
+func (s *Server) SetBlobStore(blobs blob.Store) {
+	s.blobs = blobs
 }
 
 func (s *Server) SetRequestId(nextFunc http.Handler) http.Handler {
@@ -80,27 +157,55 @@ func (s *Server) SetRequestId(nextFunc http.Handler) http.Handler {
 	})
 }
 
+// ReadPrimaryOnWrite keeps requests that may write off lagging replicas.
+func (s *Server) ReadPrimaryOnWrite(nextFunc http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			nextFunc.ServeHTTP(w, r)
+		default:
+			nextFunc.ServeHTTP(w, r.WithContext(store.WithPrimary(r.Context())))
+		}
+	})
+}
+
 func (s *Server) AuthenticateUser(nextFunc http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		session, err := (*s.sessions).Get(r, SessionName)
 		if err != nil {
-			s.handleError(w, r, http.StatusInternalServerError, err)
+			s.handleError(w, r, err)
 			return
 		}
 
 		id, exist := session.Values[UserIdSessionKey]
 		if !exist {
-			s.handleError(w, r, http.StatusUnauthorized, ErrNotAuthenticated)
+			s.handleError(w, r, ErrNotAuthenticated)
 			return
 		}
 
-		user, err := (*s.store).UserRepository().FindById(id.(int))
+		version, _ := session.Values[SessionVersionKey].(int)
+		users := (*s.store).UserRepository()
+		user, err := users.FindById(r.Context(), id.(int))
+		// A lagging replica or a stale cache may not know yet a user created or a session issued a moment ago.
+		if errors.Is(err, store.ErrRecordNotFound) || (err == nil && version != user.SessionVersion) {
+			user, err = users.FindById(store.WithLatest(r.Context()), id.(int))
+		}
+		if errors.Is(err, store.ErrRecordNotFound) {
+			s.handleError(w, r, ErrNotAuthenticated)
+			return
+		}
 		if err != nil {
-			s.handleError(w, r, http.StatusUnauthorized, ErrNotAuthenticated)
+			s.handleError(w, r, err)
+			return
+		}
+
+		if version != user.SessionVersion {
+			s.handleError(w, r, ErrNotAuthenticated)
 			return
 		}
 
 		newContext := context.WithValue(r.Context(), userContextKey, user)
+		newContext = context.WithValue(newContext, authenticationMethodContextKey, authenticatedBySession)
 		nextFunc.ServeHTTP(w, r.WithContext(newContext))
 	})
 }
@@ -125,46 +230,92 @@ func (s *Server) LogRequest(nextFunc http.Handler) http.Handler {
 }
 
 func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	s.router.ServeHTTP(w, r)
+	s.runtime.Load().handler.ServeHTTP(w, r)
 }
 
 // @Summary WhoAmI
-// @Tags common
+// @Tags users
 // @Description Get general info about yourself after authorization
 // @ID user-whoami
-// @Accept json
 // @Produce json
-// @Success 200 {integer} 1
-// @Failure 401 {object} error
-// @Router /authorized/whoami [get]
+// @Param If-None-Match header string false "ETag of the cached representation"
+// @Success 200 {object} UserResponse
+// @Header 200 {string} ETag "Version of the user"
+// @Success 304
+// @Failure 401 {object} Problem
+// @Router /v1/users/me [get]
 func (s *Server) handleWhoAmI() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		maybeUser := r.Context().Value(userContextKey)
 		if maybeUser == nil {
-			s.handleError(w, r, http.StatusUnauthorized, ErrNotAuthenticated)
+			s.handleError(w, r, ErrNotAuthenticated)
 			return
 		}
 		user := maybeUser.(*model.User)
-		s.respond(w, r, http.StatusOK, user)
+		if s.notModified(w, r, user) {
+			return
+		}
+		s.respond(w, r, http.StatusOK, newUserResponse(user))
+	}
+}
+
+// @Summary GetUser
+// @Tags users
+// @Description Get the user with the given id after authorization
+// @ID user-get
+// @Produce json
+// @Param id path int true "User id"
+// @Param If-None-Match header string false "ETag of the cached representation"
+// @Success 200 {object} UserResponse
+// @Header 200 {string} ETag "Version of the user"
+// @Success 304
+// @Failure 401 {object} Problem
+// @Failure 404 {object} Problem
+// @Router /v1/users/{id} [get]
+func (s *Server) handleUserGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			s.handleError(w, r, ErrNotFound.Wrap(err))
+			return
+		}
+		user, err := (*s.store).UserRepository().FindById(r.Context(), id)
+		if err != nil {
+			s.handleError(w, r, err)
+			return
+		}
+		if s.notModified(w, r, user) {
+			return
+		}
+		s.respond(w, r, http.StatusOK, newUserResponse(model.Sanitized(user)))
 	}
 }
 
 // @Summary CreateUser
-// @Tags registration
+// @Tags users
 // @Description Create new user and store in database
 // @ID user-create
 // @Accept json
 // @Produce json
 // @Param input body SignRequest true "Info about email and password"
-// @Success 201 {integer} 1
-// @Failure 400 {object} error
-// @Failure 422 {object} error
-// @Router /sign-up [post]
+// @Param Idempotency-Key header string false "Key making retries of the request return the first response"
+// @Success 201 {object} UserResponse
+// @Header 201 {string} Location "URL of the created user"
+// @Failure 400 {object} Problem
+// @Failure 409 {object} Problem
+// @Failure 413 {object} Problem
+// @Failure 415 {object} Problem
+// @Failure 422 {object} Problem
+// @Router /v1/users [post]
 func (s *Server) handleUserCreate() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		userMeta := &SignRequest{}
-		if err := json.NewDecoder(r.Body).Decode(userMeta); err != nil {
-			s.handleError(w, r, http.StatusBadRequest, err)
+		if err := s.decodeJson(w, r, userMeta); err != nil {
+			s.handleError(w, r, err)
+			return
+		}
+		if err := userMeta.Validate(); err != nil {
+			s.handleError(w, r, err)
 			return
 		}
 		user := &model.User{
@@ -173,50 +324,64 @@ func (s *Server) handleUserCreate() http.HandlerFunc {
 				Original: userMeta.Password,
 			},
 		}
-		err := (*s.store).UserRepository().Create(user)
+		err := (*s.store).UserRepository().Create(r.Context(), user)
 		if err != nil {
-			s.handleError(w, r, http.StatusUnprocessableEntity, err)
+			s.handleError(w, r, err)
 			return
 		}
-		s.respond(w, r, http.StatusCreated, model.Sanitized(user))
+		w.Header().Set("Location", fmt.Sprintf("/v1/users/%d", user.Id))
+		s.respond(w, r, http.StatusCreated, newUserResponse(model.Sanitized(user)))
 	}
 }
 
 // @Summary CreateSession
-// @Tags authentication
+// @Tags sessions
 // @Description Create new session for existing user
 // @ID session-create
 // @Accept json
 // @Produce json
 // @Param input body SignRequest true "Info about email and password"
 // @Success 200
-// @Failure 400 {object} error
-// @Failure 401 {object} error
-// @Failure 500 {object} error
-// @Router /sign-in [post]
+// @Failure 400 {object} Problem
+// @Failure 401 {object} Problem
+// @Failure 413 {object} Problem
+// @Failure 415 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /v1/sessions [post]
 func (s *Server) handleSessionCreate() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		userMeta := &SignRequest{}
-		if err := json.NewDecoder(r.Body).Decode(userMeta); err != nil {
-			s.handleError(w, r, http.StatusBadRequest, err)
+		if err := s.decodeJson(w, r, userMeta); err != nil {
+			s.handleError(w, r, err)
+			return
+		}
+		user, err := (*s.store).UserRepository().FindByEmail(r.Context(), userMeta.Email)
+		if err != nil && !errors.Is(err, store.ErrRecordNotFound) {
+			s.handleError(w, r, err)
 			return
 		}
-		user, err := (*s.store).UserRepository().FindByEmail(userMeta.Email)
 		if err != nil || !user.HasSamePassword(userMeta.Password) {
-			s.handleError(w, r, http.StatusUnauthorized, ErrIncorrectEmailOrPassword)
+			s.handleError(w, r, ErrIncorrectEmailOrPassword)
+			return
+		}
+		if err := (*s.store).UserRepository().RecordLogin(r.Context(), user); err != nil {
+			s.handleError(w, r, err)
 			return
 		}
 
 		session, err := (*s.sessions).Get(r, SessionName)
 		if err != nil {
-			s.handleError(w, r, http.StatusInternalServerError, ErrIncorrectEmailOrPassword)
+			s.handleError(w, r, err)
 			return
 		}
 
 		session.Values[UserIdSessionKey] = user.Id
+		session.Values[SessionVersionKey] = user.SessionVersion
+		// A fresh CSRF token is issued for every new session.
+		delete(session.Values, CsrfTokenSessionKey)
 		err = (*s.sessions).Save(r, w, session)
 		if err != nil {
-			s.handleError(w, r, http.StatusInternalServerError, ErrIncorrectEmailOrPassword)
+			s.handleError(w, r, err)
 			return
 		}
 
@@ -225,28 +390,29 @@ func (s *Server) handleSessionCreate() http.HandlerFunc {
 }
 
 // @Summary SessionLogout
-// @Tags common
+// @Tags sessions
 // @Description Log out from current session after authorization
 // @ID session-logout
-// @Accept json
-// @Produce json
 // @Success 200
-// @Failure 400 {object} error
-// @Failure 500 {object} error
-// @Router /authorized/logout [put]
+// @Failure 401 {object} Problem
+// @Failure 403 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /v1/sessions [delete]
 func (s *Server) handleSessionLogout() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		session, err := (*s.sessions).Get(r, SessionName)
 		if err != nil {
-			s.handleError(w, r, http.StatusInternalServerError, err)
+			s.handleError(w, r, err)
 			return
 		}
 
 		delete(session.Values, UserIdSessionKey)
+		delete(session.Values, SessionVersionKey)
+		delete(session.Values, CsrfTokenSessionKey)
 
 		err = (*s.sessions).Save(r, w, session)
 		if err != nil {
-			s.handleError(w, r, http.StatusInternalServerError, err)
+			s.handleError(w, r, err)
 			return
 		}
 
@@ -255,117 +421,151 @@ func (s *Server) handleSessionLogout() http.HandlerFunc {
 }
 
 // @Summary AllUsers
-// @Tags common
+// @Tags users
 // @Description Get all existing users
 // @ID users-get-all
-// @Accept json
 // @Produce json
-// @Success 200
-// @Failure 500 {object} error
-// @Router /authorized/users [get]
+// @Success 200 {array} UserResponse
+// @Failure 401 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /v1/users [get]
 func (s *Server) handleUsersGetAll() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		users, err := (*s.store).UserRepository().AllUsers()
+		users, err := (*s.store).UserRepository().AllUsers(r.Context())
 		if err != nil {
-			s.handleError(w, r, http.StatusInternalServerError, err)
+			s.handleError(w, r, err)
 			return
 		}
-		s.respond(w, r, http.StatusOK, users)
+		responses := make([]*UserResponse, 0, len(users))
+		for _, user := range users {
+			responses = append(responses, newUserResponse(user))
+		}
+		s.respond(w, r, http.StatusOK, responses)
 	}
 }
 
 // @Summary UpdateUser
-// @Tags common
-// @Description Update yourself after authorization
+// @Tags users
+// @Deprecated
+// @Description Update yourself after authorization, replaced by PATCH /v1/users/me
 // @ID users-update
 // @Accept json
 // @Produce json
-// @Param input body SignRequest true "New email or password"
+// @Param input body UpdateRequest true "New email, password or profile fields"
+// @Param If-Match header string false "ETag of the user being updated"
 // @Success 200
-// @Failure 400 {object} error
-// @Failure 401 {object} error
-// @Router /authorized/update [post]
+// @Failure 400 {object} Problem
+// @Failure 401 {object} Problem
+// @Failure 403 {object} Problem
+// @Failure 409 {object} Problem
+// @Failure 412 {object} Problem
+// @Failure 413 {object} Problem
+// @Failure 415 {object} Problem
+// @Failure 422 {object} Problem
+// @Failure 428 {object} Problem
 // @Router /authorized/update [put]
 func (s *Server) handleUserUpdate() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		maybeContextUser := r.Context().Value(userContextKey)
 		if maybeContextUser == nil {
-			s.handleError(w, r, http.StatusUnauthorized, ErrNotAuthenticated)
+			s.handleError(w, r, ErrNotAuthenticated)
 			return
 		}
 
 		contextUser := maybeContextUser.(*model.User)
 
-		userMeta := &SignRequest{}
-		if err := json.NewDecoder(r.Body).Decode(userMeta); err != nil {
-			s.handleError(w, r, http.StatusBadRequest, err)
+		userMeta := &UpdateRequest{}
+		if err := s.decodeJson(w, r, userMeta); err != nil {
+			s.handleError(w, r, err)
 			return
 		}
 
-		if userMeta.Email == "" && userMeta.Password == "" {
-			s.handleError(w, r, http.StatusBadRequest, ErrNonEmptyBodyRequired)
+		if userMeta.empty() {
+			s.handleError(w, r, ErrNonEmptyBodyRequired)
+			return
+		}
+		if err := userMeta.Validate(); err != nil {
+			s.handleError(w, r, err)
+			return
+		}
+		if err := s.checkIfMatch(r, contextUser); err != nil {
+			s.handleError(w, r, err)
 			return
 		}
 
-		finalEmail := contextUser.Email
+		updated := *contextUser
+		user := &updated
 		if userMeta.Email != "" {
-			finalEmail = userMeta.Email
+			user.Email = userMeta.Email
 		}
-		finalPassword := contextUser.Password
 		if userMeta.Password != "" {
-			finalPassword = &model.Password{
+			user.Password = &model.Password{
 				Original: userMeta.Password,
 			}
 		}
-		user := &model.User{
-			Id:       contextUser.Id,
-			Email:    finalEmail,
-			Password: finalPassword,
+		if userMeta.DisplayName != nil {
+			user.DisplayName = *userMeta.DisplayName
+		}
+		if userMeta.Username != nil {
+			user.Username = *userMeta.Username
+		}
+		if userMeta.Locale != nil {
+			user.Locale = *userMeta.Locale
+		}
+		if userMeta.Timezone != nil {
+			user.Timezone = *userMeta.Timezone
 		}
 
-		err := (*s.store).UserRepository().Update(user)
+		err := (*s.store).UserRepository().Update(r.Context(), user)
 		if err != nil {
-			s.handleError(w, r, http.StatusBadRequest, err)
+			s.handleError(w, r, err)
 			return
 		}
+		w.Header().Set("ETag", entityTag(user))
 		s.respond(w, r, http.StatusOK, nil)
 	}
 }
 
 // @Summary DeleteUser
-// @Tags common
+// @Tags users
 // @Description Delete yourself after authorization
 // @ID users-delete
-// @Accept json
-// @Produce json
+// @Param If-Match header string false "ETag of the user being deleted"
 // @Success 200
-// @Failure 401 {object} error
-// @Failure 500 {object} error
-// @Router /authorized/delete [delete]
+// @Failure 401 {object} Problem
+// @Failure 403 {object} Problem
+// @Failure 412 {object} Problem
+// @Failure 428 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /v1/users/me [delete]
 func (s *Server) handleUserDelete() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		maybeContextUser := r.Context().Value(userContextKey)
 		if maybeContextUser == nil {
-			s.handleError(w, r, http.StatusUnauthorized, ErrNotAuthenticated)
+			s.handleError(w, r, ErrNotAuthenticated)
 			return
 		}
 
 		contextUser := maybeContextUser.(*model.User)
-		err := (*s.store).UserRepository().Delete(contextUser)
+		if err := s.checkIfMatch(r, contextUser); err != nil {
+			s.handleError(w, r, err)
+			return
+		}
+		err := (*s.store).UserRepository().Delete(r.Context(), contextUser)
 		if err != nil {
-			s.handleError(w, r, http.StatusInternalServerError, err)
+			s.handleError(w, r, err)
 			return
 		}
+		s.deleteAvatar(contextUser.Id, contextUser.AvatarVersion)
 
 		s.respond(w, r, http.StatusOK, nil)
 	}
 }
 
-func (s *Server) handleError(w http.ResponseWriter, r *http.Request, status int, err error) {
-	s.respond(w, r, status, map[string]string{"error": err.Error()})
-}
-
 func (s *Server) respond(w http.ResponseWriter, r *http.Request, status int, data interface{}) {
+	if data != nil && w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	}
 	w.WriteHeader(status)
 	if data != nil {
 		err := json.NewEncoder(w).Encode(data)
@@ -2,17 +2,24 @@ package apiserver_test
 
 import (
 	"awesomeProject/internal/app/apiserver"
+	"awesomeProject/internal/app/model"
 	"awesomeProject/internal/app/store"
+	"awesomeProject/internal/app/store/cachestore"
 	"awesomeProject/internal/app/store/teststore"
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/gorilla/securecookie"
 	sessions2 "github.com/gorilla/sessions"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestServer_handleUsersCreate(t *testing.T) {
@@ -55,6 +62,7 @@ func TestServer_handleUsersCreate(t *testing.T) {
 				t.Fatal(err)
 			}
 			request, _ := http.NewRequest(http.MethodPost, "/sign-up", buf)
+			request.Header.Set("Content-Type", apiserver.JsonContentType)
 			server.ServeHTTP(recorder, request)
 			assert.Equal(t, testCase.expectedHttpCode, recorder.Code)
 		})
@@ -65,7 +73,7 @@ func TestServer_handleSessionsCreate(t *testing.T) {
 	userGen := store.TestUserHelper(t)
 	s := teststore.NewStore()
 	user := userGen()
-	err := s.UserRepository().Create(user)
+	err := s.UserRepository().Create(context.Background(), user)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -108,6 +116,7 @@ func TestServer_handleSessionsCreate(t *testing.T) {
 				t.Fatal(err)
 			}
 			request, _ := http.NewRequest(http.MethodPost, "/sign-in", buf)
+			request.Header.Set("Content-Type", apiserver.JsonContentType)
 			server.ServeHTTP(recorder, request)
 			assert.Equal(t, testCase.expectedHttpCode, recorder.Code)
 		})
@@ -119,7 +128,7 @@ func TestServer_AuthenticateUser(t *testing.T) {
 	user := userGen()
 
 	s := teststore.NewStore()
-	err := s.UserRepository().Create(user)
+	err := s.UserRepository().Create(context.Background(), user)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -141,6 +150,14 @@ func TestServer_AuthenticateUser(t *testing.T) {
 			cookies:          nil,
 			expectedHttpCode: http.StatusUnauthorized,
 		},
+		{
+			key: "revoked session",
+			cookies: map[interface{}]interface{}{
+				apiserver.UserIdSessionKey:  user.Id,
+				apiserver.SessionVersionKey: user.SessionVersion - 1,
+			},
+			expectedHttpCode: http.StatusUnauthorized,
+		},
 	}
 
 	secretKey := "secret"
@@ -165,3 +182,411 @@ func TestServer_AuthenticateUser(t *testing.T) {
 		})
 	}
 }
+
+// laggingStore serves the users as they were when it was created, unless the primary is required.
+type laggingStore struct {
+	store.Store
+	users        map[int]model.User
+	primaryReads int
+}
+
+func (s *laggingStore) UserRepository() store.UserRepository {
+	return &laggingUserRepository{UserRepository: s.Store.UserRepository(), store: s}
+}
+
+type laggingUserRepository struct {
+	store.UserRepository
+	store *laggingStore
+}
+
+func (r *laggingUserRepository) FindById(ctx context.Context, id int) (*model.User, error) {
+	if store.UsesPrimary(ctx) {
+		r.store.primaryReads++
+		return r.UserRepository.FindById(ctx, id)
+	}
+	if user, exist := r.store.users[id]; exist {
+		return &user, nil
+	}
+	return r.UserRepository.FindById(ctx, id)
+}
+
+func TestServer_AuthenticateUser_readsReplica(t *testing.T) {
+	user := store.TestUserHelper(t)()
+	s := teststore.NewStore()
+	require.NoError(t, s.UserRepository().Create(context.Background(), user))
+	lagging := &laggingStore{Store: s, users: map[int]model.User{user.Id: *user}}
+
+	server := apiserver.NewServer(lagging, sessions2.NewCookieStore([]byte(testSessionKey)))
+	recorder := httptest.NewRecorder()
+	request, _ := http.NewRequest(http.MethodGet, "/v1/users/me", nil)
+	request.Header.Set("Cookie", testSignedIn(t, user))
+	server.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, 0, lagging.primaryReads)
+}
+
+func TestServer_AuthenticateUser_newSessionVersion(t *testing.T) {
+	user := store.TestUserHelper(t)()
+	s := teststore.NewStore()
+	require.NoError(t, s.UserRepository().Create(context.Background(), user))
+	lagging := &laggingStore{Store: s, users: map[int]model.User{user.Id: *user}}
+
+	// The session was issued after a revocation the replica has not seen yet.
+	revoked := *user
+	revoked.SessionVersion++
+	require.NoError(t, s.UserRepository().Update(context.Background(), &revoked))
+	cookie := testSessionCookie(t, map[interface{}]interface{}{
+		apiserver.UserIdSessionKey:    user.Id,
+		apiserver.CsrfTokenSessionKey: testCsrfToken,
+		apiserver.SessionVersionKey:   revoked.SessionVersion,
+	})
+
+	server := apiserver.NewServer(lagging, sessions2.NewCookieStore([]byte(testSessionKey)))
+	recorder := httptest.NewRecorder()
+	request, _ := http.NewRequest(http.MethodGet, "/v1/users/me", nil)
+	request.Header.Set("Cookie", cookie)
+	server.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, 1, lagging.primaryReads)
+}
+
+// countingStore counts the users looked up by id in the wrapped store.
+type countingStore struct {
+	store.Store
+	lookups int
+}
+
+func (s *countingStore) UserRepository() store.UserRepository {
+	return &countingUserRepository{UserRepository: s.Store.UserRepository(), store: s}
+}
+
+type countingUserRepository struct {
+	store.UserRepository
+	store *countingStore
+}
+
+func (r *countingUserRepository) FindById(ctx context.Context, id int) (*model.User, error) {
+	r.store.lookups++
+	return r.UserRepository.FindById(ctx, id)
+}
+
+func TestServer_AuthenticateUser_cached(t *testing.T) {
+	user := store.TestUserHelper(t)()
+	counting := &countingStore{Store: teststore.NewStore()}
+	require.NoError(t, counting.UserRepository().Create(context.Background(), user))
+	server := apiserver.NewServer(cachestore.NewStore(counting, 10, time.Minute), sessions2.NewCookieStore([]byte(testSessionKey)))
+	cookie := testSignedIn(t, user)
+
+	for i := 0; i < 3; i++ {
+		recorder := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, "/v1/users/me", nil)
+		request.Header.Set("Cookie", cookie)
+		server.ServeHTTP(recorder, request)
+		assert.Equal(t, http.StatusOK, recorder.Code)
+	}
+
+	// Writes read the primary, the cache serves them as well.
+	recorder := httptest.NewRecorder()
+	request, _ := http.NewRequest(http.MethodPatch, "/v1/users/me", strings.NewReader(`{"email": "new@mail.com"}`))
+	request.Header.Set("Content-Type", apiserver.MergePatchContentType)
+	request.Header.Set("Cookie", cookie)
+	request.Header.Set(apiserver.CsrfTokenHeader, testCsrfToken)
+	request.Header.Set("If-Match", `"99"`)
+	server.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusPreconditionFailed, recorder.Code)
+
+	assert.Equal(t, 1, counting.lookups)
+}
+
+func TestServer_handleHealth(t *testing.T) {
+	testCases := []struct {
+		key              string
+		path             string
+		failingCheck     bool
+		shuttingDown     bool
+		expectedHttpCode int
+	}{
+		{
+			key:              "alive",
+			path:             "/healthz",
+			expectedHttpCode: http.StatusOK,
+		},
+		{
+			key:              "alive while shutting down",
+			path:             "/healthz",
+			shuttingDown:     true,
+			expectedHttpCode: http.StatusOK,
+		},
+		{
+			key:              "ready",
+			path:             "/readyz",
+			expectedHttpCode: http.StatusOK,
+		},
+		{
+			key:              "dependency unavailable",
+			path:             "/readyz",
+			failingCheck:     true,
+			expectedHttpCode: http.StatusServiceUnavailable,
+		},
+		{
+			key:              "shutting down",
+			path:             "/readyz",
+			shuttingDown:     true,
+			expectedHttpCode: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.key, func(t *testing.T) {
+			server := apiserver.NewServer(teststore.NewStore(), sessions2.NewCookieStore([]byte("xxx")))
+			server.AddHealthCheck("database", func(ctx context.Context) error {
+				if testCase.failingCheck {
+					return errors.New("connection refused")
+				}
+				return nil
+			})
+			if testCase.shuttingDown {
+				server.BeginShutdown()
+			}
+
+			recorder := httptest.NewRecorder()
+			request, _ := http.NewRequest(http.MethodGet, testCase.path, nil)
+			server.ServeHTTP(recorder, request)
+			assert.Equal(t, testCase.expectedHttpCode, recorder.Code)
+		})
+	}
+}
+
+func TestServer_ApplyConfig(t *testing.T) {
+	server := apiserver.NewServer(teststore.NewStore(), sessions2.NewCookieStore([]byte("xxx")))
+	allowedOrigin := func(origin string) string {
+		recorder := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, "/healthz", nil)
+		request.Header.Set("Origin", origin)
+		server.ServeHTTP(recorder, request)
+		return recorder.Header().Get("Access-Control-Allow-Origin")
+	}
+	assert.Equal(t, "", allowedOrigin("https://example.com"))
+
+	config := apiserver.NewConfig()
+	config.CorsAllowedOrigins = []string{"https://app.example.com"}
+	assert.NoError(t, server.ApplyConfig(config))
+	assert.Equal(t, "", allowedOrigin("https://example.com"))
+	assert.Equal(t, "https://app.example.com", allowedOrigin("https://app.example.com"))
+
+	invalid := apiserver.NewConfig()
+	invalid.LogLevel = "loud"
+	assert.Error(t, server.ApplyConfig(invalid))
+	assert.Equal(t, "https://app.example.com", allowedOrigin("https://app.example.com"))
+}
+
+func TestServer_v1Routes(t *testing.T) {
+	userGen := store.TestUserHelper(t)
+	user := userGen()
+
+	s := teststore.NewStore()
+	if err := s.UserRepository().Create(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		key              string
+		method           string
+		path             string
+		body             string
+		expectedHttpCode int
+	}{
+		{
+			key:              "create user",
+			method:           http.MethodPost,
+			path:             "/v1/users",
+			body:             `{"email": "new@mail.com", "password": "1234567890"}`,
+			expectedHttpCode: http.StatusCreated,
+		},
+		{
+			key:              "list users",
+			method:           http.MethodGet,
+			path:             "/v1/users",
+			expectedHttpCode: http.StatusOK,
+		},
+		{
+			key:              "current user",
+			method:           http.MethodGet,
+			path:             "/v1/users/me",
+			expectedHttpCode: http.StatusOK,
+		},
+		{
+			key:              "user by id",
+			method:           http.MethodGet,
+			path:             fmt.Sprintf("/v1/users/%d", user.Id),
+			expectedHttpCode: http.StatusOK,
+		},
+		{
+			key:              "missing user",
+			method:           http.MethodGet,
+			path:             "/v1/users/999",
+			expectedHttpCode: http.StatusNotFound,
+		},
+		{
+			key:              "sign in",
+			method:           http.MethodPost,
+			path:             "/v1/sessions",
+			body:             fmt.Sprintf(`{"email": %q, "password": %q}`, user.Email, "super1234pass"),
+			expectedHttpCode: http.StatusOK,
+		},
+		{
+			key:              "method not allowed",
+			method:           http.MethodPut,
+			path:             "/v1/users/me",
+			expectedHttpCode: http.StatusMethodNotAllowed,
+		},
+	}
+
+	server := apiserver.NewServer(s, sessions2.NewCookieStore([]byte(testSessionKey)))
+	cookie := testSessionCookie(t, map[interface{}]interface{}{
+		apiserver.UserIdSessionKey: user.Id,
+	})
+
+	for _, testCase := range testCases {
+		t.Run(testCase.key, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			request, _ := http.NewRequest(testCase.method, testCase.path, bytes.NewBufferString(testCase.body))
+			request.Header.Set("Content-Type", apiserver.JsonContentType)
+			request.Header.Set("Cookie", cookie)
+			server.ServeHTTP(recorder, request)
+
+			assert.Equal(t, testCase.expectedHttpCode, recorder.Code)
+			assert.Empty(t, recorder.Header().Get("Deprecation"))
+		})
+	}
+}
+
+func TestServer_ReadPrimaryOnWrite(t *testing.T) {
+	server := apiserver.NewServer(teststore.NewStore(), sessions2.NewCookieStore([]byte("xxx")))
+
+	testCases := []struct {
+		method          string
+		expectedPrimary bool
+	}{
+		{method: http.MethodGet, expectedPrimary: false},
+		{method: http.MethodHead, expectedPrimary: false},
+		{method: http.MethodPost, expectedPrimary: true},
+		{method: http.MethodPatch, expectedPrimary: true},
+		{method: http.MethodDelete, expectedPrimary: true},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.method, func(t *testing.T) {
+			var primary bool
+			handler := server.ReadPrimaryOnWrite(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				primary = store.UsesPrimary(r.Context())
+			}))
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(testCase.method, "/v1/users/me", nil))
+			assert.Equal(t, testCase.expectedPrimary, primary)
+		})
+	}
+}
+
+func TestServer_handleSessionsCreate_recordsLogin(t *testing.T) {
+	user := store.TestUserHelper(t)()
+	s := teststore.NewStore()
+	if err := s.UserRepository().Create(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+	server := apiserver.NewServer(s, sessions2.NewCookieStore([]byte("xxx")))
+
+	recorder := httptest.NewRecorder()
+	payload := fmt.Sprintf(`{"email": %q, "password": "super1234pass"}`, user.Email)
+	request, _ := http.NewRequest(http.MethodPost, "/v1/sessions", strings.NewReader(payload))
+	request.Header.Set("Content-Type", apiserver.JsonContentType)
+	server.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	loggedIn, err := s.UserRepository().FindById(context.Background(), user.Id)
+	if assert.NoError(t, err) {
+		assert.NotNil(t, loggedIn.LastLoginAt)
+	}
+}
+
+func TestServer_handleUserUpdate_profile(t *testing.T) {
+	testCases := []struct {
+		key              string
+		payload          string
+		expectedHttpCode int
+		expectedCode     string
+	}{
+		{
+			key:              "profile",
+			payload:          `{"display_name": "Andrew V.", "username": "andrvat", "locale": "ru-RU", "timezone": "Asia/Novosibirsk"}`,
+			expectedHttpCode: http.StatusOK,
+		},
+		{
+			key:              "invalid timezone",
+			payload:          `{"timezone": "Mars/Olympus_Mons"}`,
+			expectedHttpCode: http.StatusUnprocessableEntity,
+			expectedCode:     "validation_failed",
+		},
+		{
+			key:              "invalid username",
+			payload:          `{"username": "Andrew V."}`,
+			expectedHttpCode: http.StatusUnprocessableEntity,
+			expectedCode:     "validation_failed",
+		},
+		{
+			key:              "taken username",
+			payload:          `{"username": "taken"}`,
+			expectedHttpCode: http.StatusConflict,
+			expectedCode:     "username_already_exists",
+		},
+		{
+			key:              "empty",
+			payload:          `{}`,
+			expectedHttpCode: http.StatusBadRequest,
+			expectedCode:     "empty_body",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.key, func(t *testing.T) {
+			userGen := store.TestUserHelper(t)
+			other := userGen()
+			other.Email = "other@gmail.com"
+			other.Username = "taken"
+			user := userGen()
+			server, _ := testSessionServer(t, other, user)
+			cookie := testSignedIn(t, user)
+
+			recorder := httptest.NewRecorder()
+			request, _ := http.NewRequest(http.MethodPut, "/authorized/update", strings.NewReader(testCase.payload))
+			request.Header.Set("Content-Type", apiserver.JsonContentType)
+			request.Header.Set("Cookie", cookie)
+			request.Header.Set(apiserver.CsrfTokenHeader, testCsrfToken)
+			server.ServeHTTP(recorder, request)
+			assert.Equal(t, testCase.expectedHttpCode, recorder.Code)
+			if testCase.expectedHttpCode != http.StatusOK {
+				var problem apiserver.Problem
+				if assert.NoError(t, json.NewDecoder(recorder.Body).Decode(&problem)) {
+					assert.Equal(t, testCase.expectedCode, problem.Code)
+				}
+				return
+			}
+
+			recorder = httptest.NewRecorder()
+			request, _ = http.NewRequest(http.MethodGet, "/authorized/whoami", nil)
+			request.Header.Set("Cookie", cookie)
+			server.ServeHTTP(recorder, request)
+			assert.Equal(t, http.StatusOK, recorder.Code)
+
+			var whoami map[string]interface{}
+			if assert.NoError(t, json.NewDecoder(recorder.Body).Decode(&whoami)) {
+				assert.Equal(t, "Andrew V.", whoami["display_name"])
+				assert.Equal(t, "andrvat", whoami["username"])
+				assert.Equal(t, "ru-RU", whoami["locale"])
+				assert.Equal(t, "Asia/Novosibirsk", whoami["timezone"])
+				assert.NotEmpty(t, whoami["created_at"])
+				assert.NotEmpty(t, whoami["updated_at"])
+				assert.Contains(t, whoami, "last_login_at")
+			}
+		})
+	}
+}
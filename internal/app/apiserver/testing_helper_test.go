@@ -0,0 +1,45 @@
+package apiserver_test
+
+import (
+	"awesomeProject/internal/app/apiserver"
+	"awesomeProject/internal/app/model"
+	"awesomeProject/internal/app/store/teststore"
+	"context"
+	"fmt"
+	"github.com/gorilla/securecookie"
+	sessions2 "github.com/gorilla/sessions"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+const (
+	testSessionKey = "secret"
+	testCsrfToken  = "token"
+)
+
+// testSessionServer returns a server over a new teststore holding users, with sessions signed by testSessionKey.
+func testSessionServer(t *testing.T, users ...*model.User) (*apiserver.Server, *teststore.Store) {
+	t.Helper()
+	s := teststore.NewStore()
+	for _, user := range users {
+		require.NoError(t, s.UserRepository().Create(context.Background(), user))
+	}
+	return apiserver.NewServer(s, sessions2.NewCookieStore([]byte(testSessionKey))), s
+}
+
+// testSessionCookie returns the Cookie header of a session holding values.
+func testSessionCookie(t *testing.T, values map[interface{}]interface{}) string {
+	t.Helper()
+	cookie, err := securecookie.New([]byte(testSessionKey), nil).Encode(apiserver.SessionName, values)
+	require.NoError(t, err)
+	return fmt.Sprintf("%s=%s", apiserver.SessionName, cookie)
+}
+
+// testSignedIn returns the Cookie header of a session of user, requests send testCsrfToken along.
+func testSignedIn(t *testing.T, user *model.User) string {
+	t.Helper()
+	return testSessionCookie(t, map[interface{}]interface{}{
+		apiserver.UserIdSessionKey:    user.Id,
+		apiserver.CsrfTokenSessionKey: testCsrfToken,
+	})
+}
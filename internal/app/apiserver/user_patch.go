@@ -0,0 +1,155 @@
+package apiserver
+
+import (
+	"awesomeProject/internal/app/model"
+	"encoding/json"
+	"errors"
+	jsonpatch "github.com/evanphx/json-patch"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"net/http"
+)
+
+const (
+	MergePatchContentType = "application/merge-patch+json"
+	JsonPatchContentType  = "application/json-patch+json"
+)
+
+// userDocument is the part of a user its owner may patch, the password can only be added.
+type userDocument struct {
+	Email       string `json:"email"`
+	Password    string `json:"password,omitempty"`
+	DisplayName string `json:"display_name"`
+	Username    string `json:"username"`
+	Locale      string `json:"locale"`
+	Timezone    string `json:"timezone"`
+}
+
+func newUserDocument(user *model.User) userDocument {
+	return userDocument{
+		Email:       user.Email,
+		DisplayName: user.DisplayName,
+		Username:    user.Username,
+		Locale:      user.Locale,
+		Timezone:    user.Timezone,
+	}
+}
+
+func (d *userDocument) Validate() error {
+	return validation.ValidateStruct(d,
+		validation.Field(&d.Email, model.EmailRules...),
+		validation.Field(&d.Password, model.PasswordRules...),
+		validation.Field(&d.DisplayName, model.DisplayNameRules...),
+		validation.Field(&d.Username, model.UsernameRules...),
+		validation.Field(&d.Locale, model.LocaleRules...),
+		validation.Field(&d.Timezone, model.TimezoneRules...),
+	)
+}
+
+// applyPatch applies a JSON Merge Patch (RFC 7396) or a JSON Patch (RFC 6902) to a JSON document.
+func applyPatch(document []byte, patch []byte, mediaType string) ([]byte, error) {
+	if mediaType == MergePatchContentType {
+		patched, err := jsonpatch.MergePatch(document, patch)
+		if err != nil {
+			return nil, ErrMalformedBody.Wrap(err)
+		}
+		return patched, nil
+	}
+
+	operations, err := jsonpatch.DecodePatch(patch)
+	if err != nil {
+		return nil, ErrMalformedBody.Wrap(err)
+	}
+	patched, err := operations.Apply(document)
+	if err != nil {
+		if errors.Is(err, jsonpatch.ErrTestFailed) || errors.Is(err, jsonpatch.ErrMissing) {
+			return nil, ErrPatchConflict.Wrap(err)
+		}
+		return nil, ErrMalformedBody.Wrap(err)
+	}
+	return patched, nil
+}
+
+// @Summary PatchUser
+// @Tags users
+// @Description Partially update yourself after authorization with a JSON Merge Patch or a JSON Patch
+// @Description applied to the {"email", "display_name", "username", "locale", "timezone"} document.
+// @Description The password is changed by adding it to the document.
+// @ID users-patch
+// @Accept application/merge-patch+json
+// @Accept application/json-patch+json
+// @Produce json
+// @Param input body userDocument true "Merge patch, or the list of JSON Patch operations"
+// @Param If-Match header string false "ETag of the user being updated"
+// @Success 200 {object} UserResponse
+// @Header 200 {string} ETag "New version of the user"
+// @Failure 400 {object} Problem
+// @Failure 401 {object} Problem
+// @Failure 403 {object} Problem
+// @Failure 409 {object} Problem
+// @Failure 412 {object} Problem
+// @Failure 413 {object} Problem
+// @Failure 415 {object} Problem
+// @Failure 422 {object} Problem
+// @Failure 428 {object} Problem
+// @Router /v1/users/me [patch]
+func (s *Server) handleUserPatch() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		maybeContextUser := r.Context().Value(userContextKey)
+		if maybeContextUser == nil {
+			s.handleError(w, r, ErrNotAuthenticated)
+			return
+		}
+		contextUser := maybeContextUser.(*model.User)
+		if err := s.checkIfMatch(r, contextUser); err != nil {
+			s.handleError(w, r, err)
+			return
+		}
+
+		patch, mediaType, err := s.readBody(w, r, MergePatchContentType, JsonPatchContentType)
+		if err != nil {
+			s.handleError(w, r, err)
+			return
+		}
+		document, err := json.Marshal(newUserDocument(contextUser))
+		if err != nil {
+			s.handleError(w, r, err)
+			return
+		}
+		patched, err := applyPatch(document, patch, mediaType)
+		if err != nil {
+			s.handleError(w, r, err)
+			return
+		}
+
+		changes := &userDocument{}
+		if err := decodeStrict(patched, changes); err != nil {
+			s.handleError(w, r, err)
+			return
+		}
+		if err := changes.Validate(); err != nil {
+			s.handleError(w, r, err)
+			return
+		}
+
+		updated := *contextUser
+		user := &updated
+		user.Email = changes.Email
+		user.DisplayName = changes.DisplayName
+		user.Username = changes.Username
+		user.Locale = changes.Locale
+		user.Timezone = changes.Timezone
+		if changes.Password != "" {
+			user.Password = &model.Password{Original: changes.Password}
+		}
+		if err := user.Validate(); err != nil {
+			s.handleError(w, r, err)
+			return
+		}
+		if err := (*s.store).UserRepository().Update(r.Context(), user); err != nil {
+			s.handleError(w, r, err)
+			return
+		}
+		w.Header().Set("ETag", entityTag(user))
+		s.respond(w, r, http.StatusOK, newUserResponse(model.Sanitized(user)))
+	}
+}
@@ -0,0 +1,114 @@
+package apiserver_test
+
+import (
+	"awesomeProject/internal/app/apiserver"
+	"awesomeProject/internal/app/store"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestServer_handleUserPatch(t *testing.T) {
+	testCases := []struct {
+		key              string
+		contentType      string
+		patch            string
+		expectedHttpCode int
+		expectedEmail    string
+		expectedPassword string
+	}{
+		{
+			key:              "merge patch",
+			contentType:      apiserver.MergePatchContentType,
+			patch:            `{"email": "new@mail.com"}`,
+			expectedHttpCode: http.StatusOK,
+			expectedEmail:    "new@mail.com",
+			expectedPassword: "super1234pass",
+		},
+		{
+			key:              "merge patch removing email",
+			contentType:      apiserver.MergePatchContentType,
+			patch:            `{"email": null}`,
+			expectedHttpCode: http.StatusUnprocessableEntity,
+		},
+		{
+			key:              "merge patch with read only field",
+			contentType:      apiserver.MergePatchContentType,
+			patch:            `{"role": "admin"}`,
+			expectedHttpCode: http.StatusBadRequest,
+		},
+		{
+			key:              "json patch",
+			contentType:      apiserver.JsonPatchContentType,
+			patch:            `[{"op": "test", "path": "/email", "value": "abc@gmail.com"}, {"op": "add", "path": "/password", "value": "new1234pass"}]`,
+			expectedHttpCode: http.StatusOK,
+			expectedEmail:    "abc@gmail.com",
+			expectedPassword: "new1234pass",
+		},
+		{
+			key:              "merge patch of profile",
+			contentType:      apiserver.MergePatchContentType,
+			patch:            `{"display_name": "Andrew V.", "username": "andrvat", "locale": "ru-RU", "timezone": "Asia/Novosibirsk"}`,
+			expectedHttpCode: http.StatusOK,
+			expectedEmail:    "abc@gmail.com",
+			expectedPassword: "super1234pass",
+		},
+		{
+			key:              "merge patch with invalid locale",
+			contentType:      apiserver.MergePatchContentType,
+			patch:            `{"locale": "english"}`,
+			expectedHttpCode: http.StatusUnprocessableEntity,
+		},
+		{
+			key:              "json patch with failed test",
+			contentType:      apiserver.JsonPatchContentType,
+			patch:            `[{"op": "test", "path": "/email", "value": "other@mail.com"}, {"op": "remove", "path": "/email"}]`,
+			expectedHttpCode: http.StatusConflict,
+		},
+		{
+			key:              "json patch with invalid password",
+			contentType:      apiserver.JsonPatchContentType,
+			patch:            `[{"op": "add", "path": "/password", "value": "short"}]`,
+			expectedHttpCode: http.StatusUnprocessableEntity,
+		},
+		{
+			key:              "malformed json patch",
+			contentType:      apiserver.JsonPatchContentType,
+			patch:            `{"email": "new@mail.com"}`,
+			expectedHttpCode: http.StatusBadRequest,
+		},
+		{
+			key:              "plain json",
+			contentType:      apiserver.JsonContentType,
+			patch:            `{"email": "new@mail.com"}`,
+			expectedHttpCode: http.StatusUnsupportedMediaType,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.key, func(t *testing.T) {
+			user := store.TestUserHelper(t)()
+			server, s := testSessionServer(t, user)
+
+			recorder := httptest.NewRecorder()
+			request, _ := http.NewRequest(http.MethodPatch, "/v1/users/me", strings.NewReader(testCase.patch))
+			request.Header.Set("Content-Type", testCase.contentType)
+			request.Header.Set("Cookie", testSignedIn(t, user))
+			request.Header.Set(apiserver.CsrfTokenHeader, testCsrfToken)
+			server.ServeHTTP(recorder, request)
+			assert.Equal(t, testCase.expectedHttpCode, recorder.Code)
+			if testCase.expectedHttpCode != http.StatusOK {
+				return
+			}
+
+			updated, err := s.UserRepository().FindById(context.Background(), user.Id)
+			if assert.NoError(t, err) {
+				assert.Equal(t, testCase.expectedEmail, updated.Email)
+				assert.True(t, updated.HasSamePassword(testCase.expectedPassword))
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package apiserver
+
+import (
+	"awesomeProject/internal/app/model"
+	"errors"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"sort"
+	"strings"
+)
+
+const (
+	validationCodePrefix = "validation_"
+	// invalidFieldCode is reported for rules that carry no code of their own.
+	invalidFieldCode = "invalid"
+)
+
+// FieldError describes a single invalid input of a request.
+type FieldError struct {
+	// Field is the JSON path of the input, nested fields are separated by dots.
+	Field   string                 `json:"field"`
+	Code    string                 `json:"code"`
+	Message string                 `json:"message"`
+	Params  map[string]interface{} `json:"params,omitempty"`
+}
+
+// fieldErrors flattens validation errors, possibly nested, into a list sorted by field path.
+func fieldErrors(prefix string, errs validation.Errors) []FieldError {
+	result := make([]FieldError, 0, len(errs))
+	for name, err := range errs {
+		path := name
+		if prefix != "" {
+			path = prefix + "." + name
+		}
+
+		var nested validation.Errors
+		var ruleError validation.Error
+		switch {
+		case errors.As(err, &nested):
+			result = append(result, fieldErrors(path, nested)...)
+		case errors.As(err, &ruleError):
+			result = append(result, FieldError{
+				Field:   path,
+				Code:    strings.TrimPrefix(ruleError.Code(), validationCodePrefix),
+				Message: ruleError.Error(),
+				Params:  ruleError.Params(),
+			})
+		default:
+			result = append(result, FieldError{
+				Field:   path,
+				Code:    invalidFieldCode,
+				Message: err.Error(),
+			})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Field < result[j].Field
+	})
+	return result
+}
+
+// Validate checks a sign up request with the rules of the user model.
+func (r *SignRequest) Validate() error {
+	return validation.ValidateStruct(r,
+		validation.Field(&r.Email, model.EmailRules...),
+		validation.Field(&r.Password, append([]validation.Rule{validation.Required}, model.PasswordRules...)...),
+	)
+}
+
+// Validate checks the fields present in an update request.
+func (r *UpdateRequest) Validate() error {
+	return validation.ValidateStruct(r,
+		validation.Field(&r.Email, validation.When(r.Email != "", model.EmailRules...)),
+		validation.Field(&r.Password, model.PasswordRules...),
+		validation.Field(&r.DisplayName, model.DisplayNameRules...),
+		validation.Field(&r.Username, model.UsernameRules...),
+		validation.Field(&r.Locale, model.LocaleRules...),
+		validation.Field(&r.Timezone, model.TimezoneRules...),
+	)
+}
@@ -0,0 +1,32 @@
+// Package blob stores files, e.g. user avatars, apart from the database.
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+)
+
+var (
+	ErrNotFound   = errors.New("blob not found")
+	ErrInvalidKey = errors.New("invalid blob key")
+)
+
+// Store keeps blobs by slash separated keys such as avatars/1/abc/64.jpg.
+type Store interface {
+	// Put replaces the blob atomically.
+	Put(ctx context.Context, key string, r io.Reader) error
+	// Open returns the content of the blob stored under key or ErrNotFound.
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the blob stored under key, deleting a missing blob is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+func validateKey(key string) error {
+	if key == "" || key == "." || !fs.ValidPath(key) {
+		return fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+	return nil
+}
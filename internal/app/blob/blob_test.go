@@ -0,0 +1,84 @@
+package blob_test
+
+import (
+	"awesomeProject/internal/app/blob"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStores(t *testing.T) {
+	testCases := []struct {
+		key      string
+		newStore func(t *testing.T) blob.Store
+	}{
+		{
+			key: "filesystem",
+			newStore: func(t *testing.T) blob.Store {
+				s, err := blob.NewFSStore(filepath.Join(t.TempDir(), "blobs"))
+				require.NoError(t, err)
+				return s
+			},
+		},
+		{
+			key: "memory",
+			newStore: func(t *testing.T) blob.Store {
+				return blob.NewMemoryStore()
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.key, func(t *testing.T) {
+			s := testCase.newStore(t)
+			ctx := context.Background()
+
+			require.NoError(t, s.Put(ctx, "avatars/1/abc/64.jpg", strings.NewReader("first")))
+			require.NoError(t, s.Put(ctx, "avatars/1/abc/64.jpg", strings.NewReader("second")))
+			assert.Equal(t, "second", read(t, s, "avatars/1/abc/64.jpg"))
+
+			require.NoError(t, s.Delete(ctx, "avatars/1/abc/64.jpg"))
+			_, err := s.Open(ctx, "avatars/1/abc/64.jpg")
+			assert.ErrorIs(t, err, blob.ErrNotFound)
+			assert.NoError(t, s.Delete(ctx, "avatars/1/abc/64.jpg"))
+
+			for _, key := range []string{"", "/avatars/1.jpg", "avatars/../1.jpg", "avatars//1.jpg"} {
+				assert.ErrorIs(t, s.Put(ctx, key, strings.NewReader("content")), blob.ErrInvalidKey, key)
+				_, err := s.Open(ctx, key)
+				assert.ErrorIs(t, err, blob.ErrInvalidKey, key)
+			}
+		})
+	}
+}
+
+func TestFSStore_Delete_removesEmptyDirectories(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "blobs")
+	s, err := blob.NewFSStore(root)
+	require.NoError(t, err)
+	ctx := context.Background()
+	require.NoError(t, s.Put(ctx, "avatars/1/abc/64.jpg", strings.NewReader("content")))
+	require.NoError(t, s.Put(ctx, "avatars/2/def/64.jpg", strings.NewReader("content")))
+
+	require.NoError(t, s.Delete(ctx, "avatars/1/abc/64.jpg"))
+	_, err = os.Stat(filepath.Join(root, "avatars", "1"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	_, err = os.Stat(filepath.Join(root, "avatars", "2", "def"))
+	assert.NoError(t, err)
+	_, err = os.Stat(root)
+	assert.NoError(t, err)
+}
+
+func read(t *testing.T, s blob.Store, key string) string {
+	t.Helper()
+	reader, err := s.Open(context.Background(), key)
+	require.NoError(t, err)
+	defer reader.Close()
+	content, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	return string(content)
+}
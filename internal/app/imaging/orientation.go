@@ -0,0 +1,131 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+)
+
+// EXIF orientations, named after the corner of the stored image shown at the top left.
+const (
+	orientationTopLeft     = 1
+	orientationTopRight    = 2
+	orientationBottomRight = 3
+	orientationBottomLeft  = 4
+	orientationLeftTop     = 5
+	orientationRightTop    = 6
+	orientationRightBottom = 7
+	orientationLeftBottom  = 8
+
+	exifOrientationTag = 0x0112
+)
+
+var exifHeader = []byte("Exif\x00\x00")
+
+// jpegOrientation reads the EXIF orientation, defaulting to as stored.
+func jpegOrientation(data []byte) int {
+	if len(data) < 2 || data[0] != 0xFF || data[1] != 0xD8 {
+		return orientationTopLeft
+	}
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return orientationTopLeft
+		}
+		marker := data[i+1]
+		switch {
+		case marker == 0xFF:
+			// Fill byte before a marker.
+			i++
+			continue
+		case marker == 0x01 || marker >= 0xD0 && marker <= 0xD8:
+			// Markers without a segment.
+			i += 2
+			continue
+		case marker == 0xDA || marker == 0xD9:
+			// The image data starts, metadata comes before it.
+			return orientationTopLeft
+		}
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if length < 2 || i+2+length > len(data) {
+			return orientationTopLeft
+		}
+		segment := data[i+4 : i+2+length]
+		if marker == 0xE1 && bytes.HasPrefix(segment, exifHeader) {
+			return exifOrientation(segment[len(exifHeader):])
+		}
+		i += 2 + length
+	}
+	return orientationTopLeft
+}
+
+// exifOrientation reads the orientation tag of the first image file directory of a TIFF structure.
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return orientationTopLeft
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return orientationTopLeft
+	}
+	if order.Uint16(tiff[2:]) != 42 {
+		return orientationTopLeft
+	}
+	offset := order.Uint32(tiff[4:])
+	if offset < 8 || uint64(offset)+2 > uint64(len(tiff)) {
+		return orientationTopLeft
+	}
+	entries := int(order.Uint16(tiff[offset:]))
+	for i := 0; i < entries; i++ {
+		entry := int(offset) + 2 + i*12
+		if entry+12 > len(tiff) {
+			return orientationTopLeft
+		}
+		if order.Uint16(tiff[entry:]) == exifOrientationTag {
+			// A SHORT value is stored in the first bytes of the value field.
+			orientation := int(order.Uint16(tiff[entry+8:]))
+			if orientation < orientationTopLeft || orientation > orientationLeftBottom {
+				return orientationTopLeft
+			}
+			return orientation
+		}
+	}
+	return orientationTopLeft
+}
+
+// orient turns a square image the way its EXIF orientation asks.
+func orient(square *image.RGBA, orientation int) image.Image {
+	if orientation == orientationTopLeft {
+		return square
+	}
+	n := square.Bounds().Dx()
+	last := n - 1
+	oriented := image.NewRGBA(square.Bounds())
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			sourceX, sourceY := x, y
+			switch orientation {
+			case orientationTopRight:
+				sourceX = last - x
+			case orientationBottomRight:
+				sourceX, sourceY = last-x, last-y
+			case orientationBottomLeft:
+				sourceY = last - y
+			case orientationLeftTop:
+				sourceX, sourceY = y, x
+			case orientationRightTop:
+				sourceX, sourceY = y, last-x
+			case orientationRightBottom:
+				sourceX, sourceY = last-y, last-x
+			case orientationLeftBottom:
+				sourceX, sourceY = last-y, x
+			}
+			oriented.SetRGBA(x, y, square.RGBAAt(sourceX, sourceY))
+		}
+	}
+	return oriented
+}
@@ -0,0 +1,81 @@
+// Package imaging turns uploaded pictures into the square JPEG thumbnails served as avatars.
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"sort"
+)
+
+const (
+	// MaxPixels bounds the size of the decoded image, a small file can decode to gigabytes of pixels.
+	MaxPixels = 40_000_000
+
+	jpegQuality = 85
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("image format is not supported")
+	ErrTooManyPixels     = fmt.Errorf("image has more than %d pixels", MaxPixels)
+)
+
+// Thumbnails re-encodes the center square of an image as JPEG in each size, dropping its metadata.
+func Thumbnails(data []byte, sizes []int) (map[int][]byte, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if errors.Is(err, image.ErrFormat) {
+		return nil, ErrUnsupportedFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > MaxPixels {
+		return nil, ErrTooManyPixels
+	}
+	source, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	orientation := orientationTopLeft
+	if format == "jpeg" {
+		orientation = jpegOrientation(data)
+	}
+
+	// Larger thumbnails are scaled from the image, smaller ones from the previous thumbnail.
+	descending := append([]int(nil), sizes...)
+	sort.Sort(sort.Reverse(sort.IntSlice(descending)))
+	square := centerSquare(source.Bounds())
+	var previous image.Image = source
+	thumbnails := make(map[int][]byte, len(sizes))
+	for _, size := range descending {
+		thumbnail := image.NewRGBA(image.Rect(0, 0, size, size))
+		// Transparent pixels are shown on white, JPEG has no transparency.
+		draw.Draw(thumbnail, thumbnail.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+		draw.CatmullRom.Scale(thumbnail, thumbnail.Bounds(), previous, square, draw.Over, nil)
+		previous, square = thumbnail, thumbnail.Bounds()
+
+		var encoded bytes.Buffer
+		if err := jpeg.Encode(&encoded, orient(thumbnail, orientation), &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return nil, err
+		}
+		thumbnails[size] = encoded.Bytes()
+	}
+	return thumbnails, nil
+}
+
+// centerSquare returns the largest square centered in bounds.
+func centerSquare(bounds image.Rectangle) image.Rectangle {
+	side := bounds.Dx()
+	if bounds.Dy() < side {
+		side = bounds.Dy()
+	}
+	min := bounds.Min.Add(image.Pt((bounds.Dx()-side)/2, (bounds.Dy()-side)/2))
+	return image.Rectangle{Min: min, Max: min.Add(image.Pt(side, side))}
+}
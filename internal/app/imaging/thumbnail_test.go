@@ -0,0 +1,147 @@
+package imaging_test
+
+import (
+	"awesomeProject/internal/app/imaging"
+	"bytes"
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+var (
+	red  = color.RGBA{R: 255, A: 255}
+	blue = color.RGBA{B: 255, A: 255}
+)
+
+// halves returns an image with a red top half and a blue bottom half.
+func halves(width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if y < height/2 {
+				img.Set(x, y, red)
+			} else {
+				img.Set(x, y, blue)
+			}
+		}
+	}
+	return img
+}
+
+// withOrientation inserts an EXIF segment with the given orientation after the start of a JPEG.
+func withOrientation(t *testing.T, data []byte, orientation uint16) []byte {
+	t.Helper()
+	var tiff bytes.Buffer
+	tiff.WriteString("MM")
+	for _, value := range []interface{}{uint16(42), uint32(8), uint16(1),
+		uint16(0x0112), uint16(3), uint32(1), orientation, uint16(0), uint32(0)} {
+		require.NoError(t, binary.Write(&tiff, binary.BigEndian, value))
+	}
+	segment := append([]byte("Exif\x00\x00"), tiff.Bytes()...)
+
+	var result bytes.Buffer
+	result.Write(data[:2])
+	result.Write([]byte{0xFF, 0xE1})
+	require.NoError(t, binary.Write(&result, binary.BigEndian, uint16(len(segment)+2)))
+	result.Write(segment)
+	result.Write(data[2:])
+	return result.Bytes()
+}
+
+func decode(t *testing.T, data []byte) image.Image {
+	t.Helper()
+	img, format, err := image.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	require.Equal(t, "jpeg", format)
+	return img
+}
+
+func assertColor(t *testing.T, expected color.RGBA, actual color.Color) {
+	t.Helper()
+	r, g, b, _ := actual.RGBA()
+	assert.InDelta(t, float64(expected.R), float64(r>>8), 40)
+	assert.InDelta(t, float64(expected.G), float64(g>>8), 40)
+	assert.InDelta(t, float64(expected.B), float64(b>>8), 40)
+}
+
+func TestThumbnails(t *testing.T) {
+	var source bytes.Buffer
+	require.NoError(t, png.Encode(&source, halves(300, 200)))
+
+	thumbnails, err := imaging.Thumbnails(source.Bytes(), []int{64, 256, 128})
+	require.NoError(t, err)
+	require.Len(t, thumbnails, 3)
+	for _, size := range []int{64, 128, 256} {
+		img := decode(t, thumbnails[size])
+		assert.Equal(t, image.Rect(0, 0, size, size), img.Bounds())
+		assertColor(t, red, img.At(size/2, size/8))
+		assertColor(t, blue, img.At(size/2, size-size/8))
+	}
+}
+
+func TestThumbnails_orientation(t *testing.T) {
+	var source bytes.Buffer
+	require.NoError(t, jpeg.Encode(&source, halves(100, 100), nil))
+
+	testCases := []struct {
+		key         string
+		orientation uint16
+		top         color.RGBA
+		left        color.RGBA
+		right       color.RGBA
+	}{
+		{key: "as stored", orientation: 1, top: red, left: red, right: red},
+		{key: "upside down", orientation: 3, top: blue, left: blue, right: blue},
+		{key: "turned clockwise", orientation: 6, left: blue, right: red},
+		{key: "turned counterclockwise", orientation: 8, left: red, right: blue},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.key, func(t *testing.T) {
+			data := withOrientation(t, source.Bytes(), testCase.orientation)
+			thumbnails, err := imaging.Thumbnails(data, []int{64})
+			require.NoError(t, err)
+			assert.NotContains(t, string(thumbnails[64]), "Exif")
+
+			img := decode(t, thumbnails[64])
+			assertColor(t, testCase.left, img.At(8, 16))
+			assertColor(t, testCase.right, img.At(56, 16))
+			if testCase.top != (color.RGBA{}) {
+				assertColor(t, testCase.top, img.At(32, 8))
+			}
+		})
+	}
+}
+
+func TestThumbnails_invalid(t *testing.T) {
+	_, err := imaging.Thumbnails([]byte("<svg></svg>"), []int{64})
+	assert.ErrorIs(t, err, imaging.ErrUnsupportedFormat)
+
+	var truncated bytes.Buffer
+	require.NoError(t, png.Encode(&truncated, halves(10, 10)))
+	_, err = imaging.Thumbnails(truncated.Bytes()[:truncated.Len()/2], []int{64})
+	assert.Error(t, err)
+
+	_, err = imaging.Thumbnails(pngHeader(10000, 10000), []int{64})
+	assert.ErrorIs(t, err, imaging.ErrTooManyPixels)
+}
+
+// pngHeader returns the start of a PNG claiming the given dimensions, without any pixel.
+func pngHeader(width, height uint32) []byte {
+	var header bytes.Buffer
+	header.WriteString("\x89PNG\r\n\x1a\n")
+	chunk := []byte("IHDR")
+	chunk = binary.BigEndian.AppendUint32(chunk, width)
+	chunk = binary.BigEndian.AppendUint32(chunk, height)
+	chunk = append(chunk, 8, 2, 0, 0, 0)
+	_ = binary.Write(&header, binary.BigEndian, uint32(len(chunk)-4))
+	header.Write(chunk)
+	_ = binary.Write(&header, binary.BigEndian, crc32.ChecksumIEEE(chunk))
+	return header.Bytes()
+}
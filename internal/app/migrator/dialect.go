@@ -0,0 +1,68 @@
+package migrator
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Dialect holds the statements of the migrator that differ between databases.
+type Dialect interface {
+	createHistoryTable() string
+	tableExistsQuery() string
+	lock(ctx context.Context, conn *sql.Conn) error
+	unlock(conn *sql.Conn)
+}
+
+var (
+	// Postgres serializes the instances migrating the same database with an advisory lock.
+	Postgres Dialect = postgres{}
+	// SQLite relies on the transaction of each migration, the file is expected to be used by a single instance.
+	SQLite Dialect = sqlite{}
+)
+
+type postgres struct{}
+
+func (postgres) createHistoryTable() string {
+	return `CREATE TABLE IF NOT EXISTS schema_history
+(
+    version    bigint      not null primary key,
+    name       varchar     not null,
+    checksum   varchar     not null,
+    applied_at timestamptz not null default now()
+)`
+}
+
+func (postgres) tableExistsQuery() string {
+	return "SELECT to_regclass($1) IS NOT NULL"
+}
+
+func (postgres) lock(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", advisoryLockId)
+	return err
+}
+
+func (postgres) unlock(conn *sql.Conn) {
+	_, _ = conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", advisoryLockId)
+}
+
+type sqlite struct{}
+
+func (sqlite) createHistoryTable() string {
+	return `CREATE TABLE IF NOT EXISTS schema_history
+(
+    version    integer   not null primary key,
+    name       text      not null,
+    checksum   text      not null,
+    applied_at timestamp not null default CURRENT_TIMESTAMP
+)`
+}
+
+func (sqlite) tableExistsQuery() string {
+	return "SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = $1)"
+}
+
+func (sqlite) lock(context.Context, *sql.Conn) error {
+	return nil
+}
+
+func (sqlite) unlock(*sql.Conn) {}
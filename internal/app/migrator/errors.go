@@ -0,0 +1,11 @@
+package migrator
+
+import "errors"
+
+var (
+	ErrUnknownVersion    = errors.New("unknown migration version")
+	ErrChecksumMismatch  = errors.New("applied migration differs from the embedded one")
+	ErrPendingMigration  = errors.New("migration is not applied")
+	ErrIrreversible      = errors.New("migration has no down script")
+	ErrDirtyLegacySchema = errors.New("legacy schema_migrations table is dirty, fix it manually")
+)
@@ -0,0 +1,101 @@
+package migrator
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+type historyRecord struct {
+	version   uint
+	name      string
+	checksum  string
+	appliedAt time.Time
+}
+
+func ensureHistoryTable(ctx context.Context, conn *sql.Conn, dialect Dialect) error {
+	_, err := conn.ExecContext(ctx, dialect.createHistoryTable())
+	return err
+}
+
+func tableExists(ctx context.Context, conn *sql.Conn, dialect Dialect, name string) (bool, error) {
+	var exist bool
+	if err := conn.QueryRowContext(ctx, dialect.tableExistsQuery(), name).Scan(&exist); err != nil {
+		return false, err
+	}
+	return exist, nil
+}
+
+func readHistory(ctx context.Context, conn *sql.Conn, dialect Dialect) (map[uint]historyRecord, error) {
+	applied := make(map[uint]historyRecord)
+	exist, err := tableExists(ctx, conn, dialect, "schema_history")
+	if err != nil || !exist {
+		return applied, err
+	}
+
+	rows, err := conn.QueryContext(ctx, "SELECT version, name, checksum, applied_at FROM schema_history")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var record historyRecord
+		if err := rows.Scan(&record.version, &record.name, &record.checksum, &record.appliedAt); err != nil {
+			return nil, err
+		}
+		applied[record.version] = record
+	}
+	return applied, rows.Err()
+}
+
+// readLegacyVersion returns the version of the migrate/migrate tool, or 0 without its table.
+func readLegacyVersion(ctx context.Context, conn *sql.Conn, dialect Dialect) (uint, error) {
+	exist, err := tableExists(ctx, conn, dialect, "schema_migrations")
+	if err != nil || !exist {
+		return 0, err
+	}
+
+	var version uint
+	var dirty bool
+	err = conn.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if dirty {
+		return 0, fmt.Errorf("%w: legacy version %d", ErrDirtyLegacySchema, version)
+	}
+	return version, nil
+}
+
+// adoptLegacyHistory imports and drops the schema_migrations table of the migrate/migrate tool.
+func (m *Migrator) adoptLegacyHistory(ctx context.Context, conn *sql.Conn) error {
+	exist, err := tableExists(ctx, conn, m.dialect, "schema_migrations")
+	if err != nil || !exist {
+		return err
+	}
+	version, err := readLegacyVersion(ctx, conn, m.dialect)
+	if err != nil {
+		return err
+	}
+
+	return inTx(ctx, conn, func(tx *sql.Tx) error {
+		for _, migration := range m.migrations {
+			if migration.Version > version {
+				break
+			}
+			_, err := tx.ExecContext(ctx,
+				"INSERT INTO schema_history (version, name, checksum) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING",
+				migration.Version, migration.Name, migration.Checksum)
+			if err != nil {
+				return err
+			}
+		}
+		_, err := tx.ExecContext(ctx, "DROP TABLE schema_migrations")
+		return err
+	})
+}
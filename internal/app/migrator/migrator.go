@@ -0,0 +1,361 @@
+package migrator
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// advisoryLockId serializes instances migrating the same postgres database.
+const advisoryLockId = 5544_0001
+
+var migrationFileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version  uint
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+type Status struct {
+	Version   uint
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+	// Modified is set when the applied migration differs from the embedded one.
+	Modified bool
+	// Unknown is set when the database contains a migration the binary does not know about.
+	Unknown bool
+	// Legacy is set when the migration is recorded only by the migrate/migrate tool.
+	Legacy bool
+}
+
+type Migrator struct {
+	db         *sql.DB
+	dialect    Dialect
+	migrations []Migration
+}
+
+// New creates a migrator of a postgres database.
+func New(db *sql.DB, fsys fs.FS) (*Migrator, error) {
+	return NewWithDialect(db, fsys, Postgres)
+}
+
+func NewWithDialect(db *sql.DB, fsys fs.FS, dialect Dialect) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{
+		db:         db,
+		dialect:    dialect,
+		migrations: migrations,
+	}, nil
+}
+
+// Load reads migrations named as NNNNNN_name.up.sql and NNNNNN_name.down.sql from the root of fsys.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[uint]*Migration)
+	for _, entry := range entries {
+		matches := migrationFileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || matches == nil {
+			continue
+		}
+		version, err := strconv.ParseUint(matches[1], 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		content, err := fs.ReadFile(fsys, path.Clean(entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, exist := byVersion[uint(version)]
+		if !exist {
+			migration = &Migration{Version: uint(version), Name: matches[2]}
+			byVersion[uint(version)] = migration
+		}
+		if migration.Name != matches[2] {
+			return nil, fmt.Errorf("migration %d has conflicting names %q and %q", version, migration.Name, matches[2])
+		}
+		if matches[3] == "up" {
+			migration.Up = string(content)
+			migration.Checksum = checksum(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up script", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+func checksum(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// Latest returns the version of the newest embedded migration.
+func (m *Migrator) Latest() uint {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Up applies all pending migrations.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.Goto(ctx, m.Latest())
+}
+
+// Down reverts the given number of most recently applied migrations.
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.verify(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0 && steps > 0; i-- {
+			migration := m.migrations[i]
+			if _, exist := applied[migration.Version]; !exist {
+				continue
+			}
+			if err := m.revert(ctx, conn, migration); err != nil {
+				return err
+			}
+			steps--
+		}
+		return nil
+	})
+}
+
+// Goto applies or reverts migrations until the database is at the given version.
+func (m *Migrator) Goto(ctx context.Context, version uint) error {
+	if version != 0 && m.find(version) == nil {
+		return fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+	}
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.verify(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			migration := m.migrations[i]
+			if _, exist := applied[migration.Version]; exist && migration.Version > version {
+				if err := m.revert(ctx, conn, migration); err != nil {
+					return err
+				}
+			}
+		}
+		for _, migration := range m.migrations {
+			if _, exist := applied[migration.Version]; !exist && migration.Version <= version {
+				if err := m.apply(ctx, conn, migration); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// Status only reads the database, a legacy history is adopted by the next run.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	applied, err := readHistory(ctx, conn, m.dialect)
+	if err != nil {
+		return nil, err
+	}
+	legacyVersion, err := readLegacyVersion(ctx, conn, m.dialect)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []Status
+	for _, migration := range m.migrations {
+		status := Status{Version: migration.Version, Name: migration.Name}
+		if record, exist := applied[migration.Version]; exist {
+			status.Applied = true
+			status.AppliedAt = record.appliedAt
+			status.Modified = record.checksum != migration.Checksum
+			delete(applied, migration.Version)
+		} else if migration.Version <= legacyVersion {
+			status.Applied = true
+			status.Legacy = true
+		}
+		statuses = append(statuses, status)
+	}
+	for _, record := range applied {
+		statuses = append(statuses, Status{
+			Version:   record.version,
+			Name:      record.name,
+			Applied:   true,
+			AppliedAt: record.appliedAt,
+			Unknown:   true,
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+	return statuses, nil
+}
+
+// Check verifies that every embedded migration is applied unchanged and nothing else is, counting legacy ones as Status does.
+func (m *Migrator) Check(ctx context.Context) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	applied, err := m.verify(ctx, conn)
+	if err != nil {
+		return err
+	}
+	legacyVersion, err := readLegacyVersion(ctx, conn, m.dialect)
+	if err != nil {
+		return err
+	}
+	for _, migration := range m.migrations {
+		if _, exist := applied[migration.Version]; !exist && migration.Version > legacyVersion {
+			return fmt.Errorf("%w: %d_%s", ErrPendingMigration, migration.Version, migration.Name)
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) find(version uint) *Migration {
+	for i := range m.migrations {
+		if m.migrations[i].Version == version {
+			return &m.migrations[i]
+		}
+	}
+	return nil
+}
+
+// verify refuses applied migrations that were edited or are unknown.
+func (m *Migrator) verify(ctx context.Context, conn *sql.Conn) (map[uint]historyRecord, error) {
+	applied, err := readHistory(ctx, conn, m.dialect)
+	if err != nil {
+		return nil, err
+	}
+	for version, record := range applied {
+		migration := m.find(version)
+		if migration == nil {
+			return nil, fmt.Errorf("%w: %d_%s", ErrUnknownVersion, version, record.name)
+		}
+		if migration.Checksum != record.checksum {
+			return nil, fmt.Errorf("%w: %d_%s", ErrChecksumMismatch, version, record.name)
+		}
+	}
+	return applied, nil
+}
+
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, migration Migration) error {
+	if ownsTransaction(migration.Up) {
+		if _, err := conn.ExecContext(ctx, migration.Up); err != nil {
+			return fmt.Errorf("apply %d_%s: %w", migration.Version, migration.Name, err)
+		}
+		_, err := conn.ExecContext(ctx,
+			"INSERT INTO schema_history (version, name, checksum) VALUES ($1, $2, $3)",
+			migration.Version, migration.Name, migration.Checksum)
+		return err
+	}
+	return inTx(ctx, conn, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, migration.Up); err != nil {
+			return fmt.Errorf("apply %d_%s: %w", migration.Version, migration.Name, err)
+		}
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO schema_history (version, name, checksum) VALUES ($1, $2, $3)",
+			migration.Version, migration.Name, migration.Checksum)
+		return err
+	})
+}
+
+func (m *Migrator) revert(ctx context.Context, conn *sql.Conn, migration Migration) error {
+	if migration.Down == "" {
+		return fmt.Errorf("%w: %d_%s", ErrIrreversible, migration.Version, migration.Name)
+	}
+	if ownsTransaction(migration.Down) {
+		if _, err := conn.ExecContext(ctx, migration.Down); err != nil {
+			return fmt.Errorf("revert %d_%s: %w", migration.Version, migration.Name, err)
+		}
+		_, err := conn.ExecContext(ctx, "DELETE FROM schema_history WHERE version = $1", migration.Version)
+		return err
+	}
+	return inTx(ctx, conn, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, migration.Down); err != nil {
+			return fmt.Errorf("revert %d_%s: %w", migration.Version, migration.Name, err)
+		}
+		_, err := tx.ExecContext(ctx, "DELETE FROM schema_history WHERE version = $1", migration.Version)
+		return err
+	})
+}
+
+// withLock runs fn on a single connection holding the migration lock of the dialect.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err := m.dialect.lock(ctx, conn); err != nil {
+		return err
+	}
+	defer m.dialect.unlock(conn)
+
+	if err := ensureHistoryTable(ctx, conn, m.dialect); err != nil {
+		return err
+	}
+	if err := m.adoptLegacyHistory(ctx, conn); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+// ownsTransaction reports scripts written for migrate/migrate, which begin and commit themselves.
+func ownsTransaction(script string) bool {
+	return strings.HasPrefix(strings.ToUpper(strings.TrimSpace(script)), "BEGIN;")
+}
+
+func inTx(ctx context.Context, conn *sql.Conn, fn func(tx *sql.Tx) error) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
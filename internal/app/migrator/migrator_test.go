@@ -0,0 +1,154 @@
+package migrator_test
+
+import (
+	"awesomeProject/internal/app/migrator"
+	"awesomeProject/migrations"
+	"context"
+	"database/sql"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoad(t *testing.T) {
+	testCases := []struct {
+		key         string
+		files       fstest.MapFS
+		expectedErr bool
+		expected    []uint
+	}{
+		{
+			key: "ordered by version",
+			files: fstest.MapFS{
+				"000002_second.up.sql":   {Data: []byte("SELECT 2")},
+				"000002_second.down.sql": {Data: []byte("SELECT -2")},
+				"000001_first.up.sql":    {Data: []byte("SELECT 1")},
+				"migrations.go":          {Data: []byte("package migrations")},
+			},
+			expected: []uint{1, 2},
+		},
+		{
+			key: "missing up script",
+			files: fstest.MapFS{
+				"000001_first.down.sql": {Data: []byte("SELECT -1")},
+			},
+			expectedErr: true,
+		},
+		{
+			key: "conflicting names",
+			files: fstest.MapFS{
+				"000001_first.up.sql":   {Data: []byte("SELECT 1")},
+				"000001_other.down.sql": {Data: []byte("SELECT -1")},
+			},
+			expectedErr: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.key, func(t *testing.T) {
+			loaded, err := migrator.Load(testCase.files)
+			if testCase.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			versions := make([]uint, 0, len(loaded))
+			for _, migration := range loaded {
+				versions = append(versions, migration.Version)
+				assert.NotEmpty(t, migration.Checksum)
+			}
+			assert.Equal(t, testCase.expected, versions)
+		})
+	}
+}
+
+func TestLoad_Embedded(t *testing.T) {
+	loaded, err := migrator.Load(migrations.FS)
+	assert.NoError(t, err)
+	for _, migration := range loaded {
+		assert.NotEmpty(t, migration.Down, "migration %d_%s", migration.Version, migration.Name)
+	}
+}
+
+var legacyFiles = fstest.MapFS{
+	"000001_first.up.sql":    {Data: []byte("CREATE TABLE first (id integer)")},
+	"000001_first.down.sql":  {Data: []byte("DROP TABLE first")},
+	"000002_second.up.sql":   {Data: []byte("BEGIN;\nCREATE TABLE second (id integer);\nCOMMIT;")},
+	"000002_second.down.sql": {Data: []byte("BEGIN;\nDROP TABLE second;\nCOMMIT;")},
+}
+
+func openSqlite(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite3", "file:"+filepath.Join(t.TempDir(), "migrator.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+func tableExists(t *testing.T, db *sql.DB, name string) bool {
+	t.Helper()
+	var exist bool
+	require.NoError(t, db.QueryRow("SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = ?)", name).Scan(&exist))
+	return exist
+}
+
+func TestMigrator_Status_legacyIsReadOnly(t *testing.T) {
+	db := openSqlite(t)
+	_, err := db.Exec("CREATE TABLE schema_migrations (version bigint, dirty boolean); INSERT INTO schema_migrations VALUES (1, false)")
+	require.NoError(t, err)
+	schema, err := migrator.NewWithDialect(db, legacyFiles, migrator.SQLite)
+	require.NoError(t, err)
+
+	statuses, err := schema.Status(context.Background())
+	require.NoError(t, err)
+	require.Len(t, statuses, 2)
+	assert.True(t, statuses[0].Applied)
+	assert.True(t, statuses[0].Legacy)
+	assert.False(t, statuses[1].Applied)
+	assert.True(t, tableExists(t, db, "schema_migrations"))
+	assert.False(t, tableExists(t, db, "schema_history"))
+
+	require.NoError(t, schema.Up(context.Background()))
+	assert.False(t, tableExists(t, db, "schema_migrations"))
+	statuses, err = schema.Status(context.Background())
+	require.NoError(t, err)
+	for _, status := range statuses {
+		assert.True(t, status.Applied)
+		assert.False(t, status.Legacy)
+	}
+}
+
+func TestMigrator_Check_legacy(t *testing.T) {
+	db := openSqlite(t)
+	_, err := db.Exec("CREATE TABLE schema_migrations (version bigint, dirty boolean); INSERT INTO schema_migrations VALUES (1, false)")
+	require.NoError(t, err)
+	schema, err := migrator.NewWithDialect(db, legacyFiles, migrator.SQLite)
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, schema.Check(context.Background()), migrator.ErrPendingMigration)
+
+	_, err = db.Exec("UPDATE schema_migrations SET version = 2")
+	require.NoError(t, err)
+	assert.NoError(t, schema.Check(context.Background()))
+
+	_, err = db.Exec("UPDATE schema_migrations SET dirty = true")
+	require.NoError(t, err)
+	assert.ErrorIs(t, schema.Check(context.Background()), migrator.ErrDirtyLegacySchema)
+}
+
+func TestMigrator_scriptsOwningTransaction(t *testing.T) {
+	db := openSqlite(t)
+	schema, err := migrator.NewWithDialect(db, legacyFiles, migrator.SQLite)
+	require.NoError(t, err)
+
+	require.NoError(t, schema.Up(context.Background()))
+	assert.True(t, tableExists(t, db, "second"))
+	require.NoError(t, schema.Check(context.Background()))
+
+	require.NoError(t, schema.Down(context.Background(), 1))
+	assert.False(t, tableExists(t, db, "second"))
+	assert.True(t, tableExists(t, db, "first"))
+}
@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+// IdempotencyRecord is the response replayed to retries until it expires.
+type IdempotencyRecord struct {
+	// Scope is the route and the client the key was used by, the same key may be reused elsewhere.
+	Scope       string
+	Key         string
+	RequestHash string
+	// Status is zero while the first request is still being processed.
+	Status    int
+	Headers   map[string]string
+	Body      []byte
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+// IsCompleted reports whether the response of the first request is recorded.
+func (r *IdempotencyRecord) IsCompleted() bool {
+	return r.Status != 0
+}
@@ -1,9 +1,13 @@
 package model
 
 import (
-	validation "github.com/go-ozzo/ozzo-validation"
-	"github.com/go-ozzo/ozzo-validation/is"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/go-ozzo/ozzo-validation/v4/is"
 	"golang.org/x/crypto/bcrypt"
+	"regexp"
+	"time"
+	// Time zones validate the same way on every host.
+	_ "time/tzdata"
 )
 
 type Password struct {
@@ -11,10 +15,61 @@ type Password struct {
 	Encrypted string `json:"-"`
 }
 
+const (
+	RoleBasic     = "basic"
+	RoleAdmin     = "admin"
+	RoleModerator = "moderator"
+
+	DefaultLocale   = "en"
+	DefaultTimezone = "UTC"
+)
+
+// AvatarSizes are the sides in pixels of the square avatar images kept for every user.
+var AvatarSizes = []int{64, 128, 256}
+
+// EmailRules and PasswordRules are shared with the request DTOs.
+var (
+	EmailRules    = []validation.Rule{validation.Required, is.EmailFormat}
+	PasswordRules = []validation.Rule{validation.Length(8, 36)}
+)
+
+// Profile rules accept empty values, empty locales and time zones get the defaults.
+var (
+	DisplayNameRules = []validation.Rule{validation.RuneLength(0, 64)}
+	UsernameRules    = []validation.Rule{
+		validation.Length(3, 32),
+		validation.Match(regexp.MustCompile(`^[a-z0-9_]+$`)).Error("must contain only lowercase letters, digits and underscores"),
+	}
+	// LocaleRules accept BCP 47 tags such as en-US.
+	LocaleRules = []validation.Rule{
+		validation.Match(regexp.MustCompile(`^[a-z]{2,3}(-[A-Z][a-z]{3})?(-([A-Z]{2}|[0-9]{3}))?$`)).Error("must be a language tag such as en or en-US"),
+	}
+	TimezoneRules = []validation.Rule{validation.By(validateTimezone)}
+)
+
 type User struct {
 	Id       int       `json:"id"`
 	Email    string    `json:"email"`
 	Password *Password `json:"password,omitempty"`
+	Role     string    `json:"role"`
+	// SessionVersion is increased to invalidate all sessions issued to the user before.
+	SessionVersion int `json:"-"`
+	// Version is increased by every update, stale updates and deletes are refused.
+	Version int `json:"-"`
+
+	DisplayName string `json:"display_name"`
+	// Username is unique among the users having one.
+	Username string `json:"username,omitempty"`
+	Locale   string `json:"locale"`
+	Timezone string `json:"timezone"`
+
+	// CreatedAt, UpdatedAt and LastLoginAt are set by the stores.
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	LastLoginAt *time.Time `json:"last_login_at"`
+
+	// AvatarVersion is empty without an avatar and changes with its content.
+	AvatarVersion string `json:"-"`
 }
 
 func NewEmptyUser() *User {
@@ -24,6 +79,15 @@ func NewEmptyUser() *User {
 }
 
 func (u *User) BeforeCreateOrUpdate() error {
+	if u.Role == "" {
+		u.Role = RoleBasic
+	}
+	if u.Locale == "" {
+		u.Locale = DefaultLocale
+	}
+	if u.Timezone == "" {
+		u.Timezone = DefaultTimezone
+	}
 	err := u.Validate()
 	if err != nil {
 		return err
@@ -56,24 +120,35 @@ func encrypt(original string) (string, error) {
 
 func (u *User) Validate() error {
 	err := validation.ValidateStruct(u,
-		validation.Field(&u.Email, validation.Required, is.Email),
+		validation.Field(&u.Email, EmailRules...),
 		validation.Field(&u.Password),
+		validation.Field(&u.Role, validation.In(RoleBasic, RoleAdmin, RoleModerator)),
+		validation.Field(&u.DisplayName, DisplayNameRules...),
+		validation.Field(&u.Username, UsernameRules...),
+		validation.Field(&u.Locale, LocaleRules...),
+		validation.Field(&u.Timezone, TimezoneRules...),
 	)
 	return err
 }
 
+func validateTimezone(value interface{}) error {
+	value, _ = validation.Indirect(value)
+	timezone, _ := value.(string)
+	if timezone == "" {
+		return nil
+	}
+	// Local is the zone of the host, meaningless to the user.
+	if _, err := time.LoadLocation(timezone); err != nil || timezone == "Local" {
+		return validation.NewError("validation_timezone", "must be an IANA time zone such as Europe/Paris")
+	}
+	return nil
+}
+
 func (p Password) Validate() error {
+	// Stored users keep only the encrypted password, the original one is required for new passwords.
+	rules := append([]validation.Rule{validation.When(p.Encrypted == "", validation.Required)}, PasswordRules...)
 	err := validation.ValidateStruct(&p,
-		validation.Field(&p.Original,
-			validation.By(func(condition bool) validation.RuleFunc {
-				return func(value interface{}) error {
-					if condition {
-						return validation.Validate(value, validation.Required)
-					}
-					return nil
-				}
-			}(p.Encrypted == "")),
-			validation.Length(8, 36)),
+		validation.Field(&p.Original, rules...),
 	)
 	return err
 }
@@ -0,0 +1,16 @@
+package cachestore_test
+
+import (
+	"awesomeProject/internal/app/store"
+	"awesomeProject/internal/app/store/cachestore"
+	"awesomeProject/internal/app/store/storetest"
+	"awesomeProject/internal/app/store/teststore"
+	"testing"
+	"time"
+)
+
+func TestStore_conformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Store {
+		return cachestore.NewStore(teststore.NewStore(), 10, time.Minute)
+	})
+}
@@ -0,0 +1,112 @@
+package cachestore
+
+import (
+	"awesomeProject/internal/app/model"
+	"container/list"
+	"sync"
+	"time"
+)
+
+// lru keeps at most size users, evicting the least recently used one, each for at most ttl.
+type lru struct {
+	size int
+	ttl  time.Duration
+	now  func() time.Time
+
+	mutex    sync.Mutex
+	order    *list.List
+	elements map[int]*list.Element
+	// generation changes with every invalidation, so that a user read before it is not cached after it.
+	generation uint64
+}
+
+type lruEntry struct {
+	user      *model.User
+	expiresAt time.Time
+}
+
+func newLru(size int, ttl time.Duration) *lru {
+	return &lru{
+		size:     size,
+		ttl:      ttl,
+		now:      time.Now,
+		order:    list.New(),
+		elements: make(map[int]*list.Element),
+	}
+}
+
+func (c *lru) get(id int) (*model.User, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	element, exist := c.elements[id]
+	if !exist {
+		return nil, false
+	}
+	entry := element.Value.(*lruEntry)
+	if !c.now().Before(entry.expiresAt) {
+		c.order.Remove(element)
+		delete(c.elements, id)
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return entry.user, true
+}
+
+func (c *lru) currentGeneration() uint64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.generation
+}
+
+// add caches user unless the cache was invalidated since generation, and reports whether another user was evicted.
+func (c *lru) add(user *model.User, generation uint64) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if generation != c.generation {
+		return false
+	}
+	entry := &lruEntry{user: user, expiresAt: c.now().Add(c.ttl)}
+	if element, exist := c.elements[user.Id]; exist {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return false
+	}
+	c.elements[user.Id] = c.order.PushFront(entry)
+	if c.order.Len() <= c.size {
+		return false
+	}
+	oldest := c.order.Back()
+	c.order.Remove(oldest)
+	delete(c.elements, oldest.Value.(*lruEntry).user.Id)
+	return true
+}
+
+func (c *lru) remove(id int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.generation++
+	if element, exist := c.elements[id]; exist {
+		c.order.Remove(element)
+		delete(c.elements, id)
+	}
+}
+
+func (c *lru) clear() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.generation++
+	c.order.Init()
+	c.elements = make(map[int]*list.Element)
+}
+
+func (c *lru) len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.order.Len()
+}
@@ -0,0 +1,97 @@
+package cachestore
+
+import (
+	"awesomeProject/internal/app/model"
+	"awesomeProject/internal/app/store"
+	"context"
+	"sync"
+	"time"
+)
+
+// Store wraps another store and caches the users it finds by id.
+type Store struct {
+	store.Store
+	userRepository *UserRepository
+}
+
+func NewStore(next store.Store, size int, ttl time.Duration) *Store {
+	return &Store{
+		Store:          next,
+		userRepository: NewUserRepository(next.UserRepository(), size, ttl),
+	}
+}
+
+func (s *Store) UserRepository() store.UserRepository {
+	return s.userRepository
+}
+
+// CachedUsers returns the cache, e.g. to invalidate users changed by other processes or read its statistics.
+func (s *Store) CachedUsers() *UserRepository {
+	return s.userRepository
+}
+
+// WithTx bypasses the cache and invalidates the changed users once the transaction is over.
+func (s *Store) WithTx(ctx context.Context, fn func(store.Store) error) error {
+	changed := &changedUsers{}
+	defer func() {
+		for _, id := range changed.list() {
+			s.userRepository.Invalidate(id)
+		}
+	}()
+	return s.Store.WithTx(ctx, func(tx store.Store) error {
+		return fn(&txStore{Store: tx, changed: changed})
+	})
+}
+
+// txStore records the users changed through the store of a transaction.
+type txStore struct {
+	store.Store
+	changed *changedUsers
+}
+
+func (s *txStore) UserRepository() store.UserRepository {
+	return &txUserRepository{UserRepository: s.Store.UserRepository(), changed: s.changed}
+}
+
+func (s *txStore) WithTx(ctx context.Context, fn func(store.Store) error) error {
+	return s.Store.WithTx(ctx, func(tx store.Store) error {
+		return fn(&txStore{Store: tx, changed: s.changed})
+	})
+}
+
+type txUserRepository struct {
+	store.UserRepository
+	changed *changedUsers
+}
+
+func (r *txUserRepository) Update(ctx context.Context, user *model.User) error {
+	r.changed.add(user.Id)
+	return r.UserRepository.Update(ctx, user)
+}
+
+func (r *txUserRepository) Delete(ctx context.Context, user *model.User) error {
+	r.changed.add(user.Id)
+	return r.UserRepository.Delete(ctx, user)
+}
+
+func (r *txUserRepository) RecordLogin(ctx context.Context, user *model.User) error {
+	r.changed.add(user.Id)
+	return r.UserRepository.RecordLogin(ctx, user)
+}
+
+type changedUsers struct {
+	mutex sync.Mutex
+	ids   []int
+}
+
+func (c *changedUsers) add(id int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.ids = append(c.ids, id)
+}
+
+func (c *changedUsers) list() []int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.ids
+}
@@ -0,0 +1,116 @@
+package cachestore
+
+import (
+	"awesomeProject/internal/app/model"
+	"awesomeProject/internal/app/store"
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// Stats counts the lookups by id served from the cache and from the wrapped repository.
+type Stats struct {
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"`
+	Size      int    `json:"size"`
+}
+
+// UserRepository caches users found by id until they change, are invalidated or ttl passes.
+type UserRepository struct {
+	next  store.UserRepository
+	cache *lru
+
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	evictions atomic.Uint64
+}
+
+func NewUserRepository(next store.UserRepository, size int, ttl time.Duration) *UserRepository {
+	return &UserRepository{
+		next:  next,
+		cache: newLru(size, ttl),
+	}
+}
+
+func (r *UserRepository) Create(ctx context.Context, user *model.User) error {
+	return r.next.Create(ctx, user)
+}
+
+func (r *UserRepository) Update(ctx context.Context, user *model.User) error {
+	defer r.Invalidate(user.Id)
+	return r.next.Update(ctx, user)
+}
+
+func (r *UserRepository) Delete(ctx context.Context, user *model.User) error {
+	defer r.Invalidate(user.Id)
+	return r.next.Delete(ctx, user)
+}
+
+func (r *UserRepository) RecordLogin(ctx context.Context, user *model.User) error {
+	defer r.Invalidate(user.Id)
+	return r.next.RecordLogin(ctx, user)
+}
+
+func (r *UserRepository) AllUsers(ctx context.Context) ([]*model.User, error) {
+	return r.next.AllUsers(ctx)
+}
+
+func (r *UserRepository) FindByEmail(ctx context.Context, email string) (*model.User, error) {
+	return r.next.FindByEmail(ctx, email)
+}
+
+// FindById serves the user from the cache, unless ctx requires the latest version of it. Reads
+// requiring the primary are served too, invalidation keeps the cache as recent as the primary.
+func (r *UserRepository) FindById(ctx context.Context, id int) (*model.User, error) {
+	if !store.SkipsCache(ctx) {
+		if user, exist := r.cache.get(id); exist {
+			r.hits.Add(1)
+			return copyUser(user), nil
+		}
+	}
+	r.misses.Add(1)
+	// Misses read the primary, a lagging replica would cache a version that was already replaced.
+	generation := r.cache.currentGeneration()
+	user, err := r.next.FindById(store.WithPrimary(ctx), id)
+	if err != nil {
+		return nil, err
+	}
+	if r.cache.add(copyUser(user), generation) {
+		r.evictions.Add(1)
+	}
+	return user, nil
+}
+
+// Invalidate drops the cached user with the given id.
+func (r *UserRepository) Invalidate(id int) {
+	r.cache.remove(id)
+}
+
+// InvalidateAll drops every cached user.
+func (r *UserRepository) InvalidateAll() {
+	r.cache.clear()
+}
+
+func (r *UserRepository) Stats() Stats {
+	return Stats{
+		Hits:      r.hits.Load(),
+		Misses:    r.misses.Load(),
+		Evictions: r.evictions.Load(),
+		Size:      r.cache.len(),
+	}
+}
+
+// copyUser keeps the cached users apart from the ones handed to callers, which may change them.
+func copyUser(user *model.User) *model.User {
+	copied := *user
+	if user.Password != nil {
+		password := *user.Password
+		copied.Password = &password
+	}
+	if user.LastLoginAt != nil {
+		lastLoginAt := *user.LastLoginAt
+		copied.LastLoginAt = &lastLoginAt
+	}
+	return &copied
+}
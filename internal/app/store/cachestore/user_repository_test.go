@@ -0,0 +1,127 @@
+package cachestore_test
+
+import (
+	"awesomeProject/internal/app/model"
+	"awesomeProject/internal/app/store"
+	"awesomeProject/internal/app/store/cachestore"
+	"awesomeProject/internal/app/store/teststore"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestUserRepository_FindById(t *testing.T) {
+	s := cachestore.NewStore(teststore.NewStore(), 10, time.Minute)
+	userGen := store.TestUserHelper(t)
+	user := userGen()
+	assert.NoError(t, s.UserRepository().Create(context.Background(), user))
+
+	for i := 0; i < 3; i++ {
+		returnedUser, err := s.UserRepository().FindById(context.Background(), user.Id)
+		assert.NoError(t, err)
+		assert.Equal(t, user.Email, returnedUser.Email)
+	}
+	_, err := s.UserRepository().FindById(store.WithPrimary(context.Background()), user.Id)
+	assert.NoError(t, err)
+	_, err = s.UserRepository().FindById(store.WithLatest(context.Background()), user.Id)
+	assert.NoError(t, err)
+
+	stats := s.CachedUsers().Stats()
+	assert.Equal(t, uint64(3), stats.Hits)
+	assert.Equal(t, uint64(2), stats.Misses)
+	assert.Equal(t, 1, stats.Size)
+}
+
+func TestUserRepository_invalidation(t *testing.T) {
+	s := cachestore.NewStore(teststore.NewStore(), 10, time.Minute)
+	userGen := store.TestUserHelper(t)
+	user := userGen()
+	assert.NoError(t, s.UserRepository().Create(context.Background(), user))
+
+	cached, err := s.UserRepository().FindById(context.Background(), user.Id)
+	assert.NoError(t, err)
+	cached.Email = "new@mail.com"
+	assert.NoError(t, s.UserRepository().Update(context.Background(), cached))
+
+	returnedUser, err := s.UserRepository().FindById(context.Background(), user.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, "new@mail.com", returnedUser.Email)
+
+	err = s.WithTx(context.Background(), func(tx store.Store) error {
+		returnedUser.Email = "tx@mail.com"
+		return tx.UserRepository().Update(context.Background(), returnedUser)
+	})
+	assert.NoError(t, err)
+	returnedUser, err = s.UserRepository().FindById(context.Background(), user.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, "tx@mail.com", returnedUser.Email)
+
+	assert.NoError(t, s.UserRepository().Delete(context.Background(), returnedUser))
+	_, err = s.UserRepository().FindById(context.Background(), user.Id)
+	assert.ErrorIs(t, err, store.ErrRecordNotFound)
+}
+
+func TestUserRepository_eviction(t *testing.T) {
+	s := cachestore.NewStore(teststore.NewStore(), 1, 20*time.Millisecond)
+	userGen := store.TestUserHelper(t)
+	first, second := userGen(), userGen()
+	second.Email = "second@mail.com"
+	assert.NoError(t, s.UserRepository().Create(context.Background(), first))
+	assert.NoError(t, s.UserRepository().Create(context.Background(), second))
+
+	for _, id := range []int{first.Id, second.Id, first.Id} {
+		_, err := s.UserRepository().FindById(context.Background(), id)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, uint64(2), s.CachedUsers().Stats().Evictions)
+	assert.Equal(t, uint64(3), s.CachedUsers().Stats().Misses)
+
+	time.Sleep(30 * time.Millisecond)
+	_, err := s.UserRepository().FindById(context.Background(), first.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(4), s.CachedUsers().Stats().Misses)
+}
+
+// racingUserRepository runs changed after reading a user, as if a change landed before the user is cached.
+type racingUserRepository struct {
+	store.UserRepository
+	changed func()
+	primary bool
+}
+
+func (r *racingUserRepository) FindById(ctx context.Context, id int) (*model.User, error) {
+	r.primary = store.UsesPrimary(ctx)
+	user, err := r.UserRepository.FindById(ctx, id)
+	if r.changed != nil {
+		r.changed()
+		r.changed = nil
+	}
+	return user, err
+}
+
+func TestUserRepository_changedWhileFilling(t *testing.T) {
+	next := teststore.NewStore().UserRepository()
+	user := store.TestUserHelper(t)()
+	assert.NoError(t, next.Create(context.Background(), user))
+
+	racing := &racingUserRepository{UserRepository: next}
+	cached := cachestore.NewUserRepository(racing, 10, time.Minute)
+	racing.changed = func() {
+		changed := *user
+		changed.Email = "changed@mail.com"
+		assert.NoError(t, next.Update(context.Background(), &changed))
+		cached.Invalidate(user.Id)
+	}
+
+	returnedUser, err := cached.FindById(context.Background(), user.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, user.Email, returnedUser.Email)
+	assert.True(t, racing.primary)
+	assert.Equal(t, 0, cached.Stats().Size)
+
+	returnedUser, err = cached.FindById(context.Background(), user.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, "changed@mail.com", returnedUser.Email)
+	assert.Equal(t, 1, cached.Stats().Size)
+}
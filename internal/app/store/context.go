@@ -0,0 +1,30 @@
+package store
+
+import "context"
+
+type primaryContextKey struct{}
+
+type latestContextKey struct{}
+
+// WithPrimary makes the reads of ctx see the writes made before them. Caches kept up to date by
+// invalidation may still serve them.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryContextKey{}, true)
+}
+
+// WithLatest makes the reads of ctx skip the caches and read the primary database.
+func WithLatest(ctx context.Context) context.Context {
+	return context.WithValue(ctx, latestContextKey{}, true)
+}
+
+// UsesPrimary reports whether ctx requires reads from the primary database.
+func UsesPrimary(ctx context.Context) bool {
+	primary, _ := ctx.Value(primaryContextKey{}).(bool)
+	return primary || SkipsCache(ctx)
+}
+
+// SkipsCache reports whether ctx requires reads to bypass the caches.
+func SkipsCache(ctx context.Context) bool {
+	latest, _ := ctx.Value(latestContextKey{}).(bool)
+	return latest
+}
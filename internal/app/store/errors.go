@@ -3,6 +3,9 @@ package store
 import "errors"
 
 var (
-	ErrRecordNotFound   = errors.New("record not found")
-	ErrDatabaseInternal = errors.New("database internal error")
+	ErrRecordNotFound        = errors.New("record not found")
+	ErrDatabaseInternal      = errors.New("database internal error")
+	ErrEmailAlreadyExists    = errors.New("user with this email already exists")
+	ErrUsernameAlreadyExists = errors.New("user with this username already exists")
+	ErrVersionConflict       = errors.New("record was changed since it was read")
 )
@@ -0,0 +1,18 @@
+package store
+
+import (
+	"awesomeProject/internal/app/model"
+	"context"
+	"time"
+)
+
+type IdempotencyRepository interface {
+	// Reserve returns the live record holding the key, or nil after reserving it.
+	Reserve(ctx context.Context, record *model.IdempotencyRecord) (*model.IdempotencyRecord, error)
+	// Complete stores the response and the final expiry of a reserved request.
+	Complete(ctx context.Context, record *model.IdempotencyRecord) error
+	// Release forgets a reserved request, so that it can be retried.
+	Release(ctx context.Context, record *model.IdempotencyRecord) error
+	// DeleteExpired removes the records expired at the given time and returns how many there were.
+	DeleteExpired(ctx context.Context, at time.Time) (int64, error)
+}
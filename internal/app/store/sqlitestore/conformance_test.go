@@ -0,0 +1,14 @@
+package sqlitestore_test
+
+import (
+	"awesomeProject/internal/app/store"
+	"awesomeProject/internal/app/store/sqlitestore"
+	"awesomeProject/internal/app/store/storetest"
+	"testing"
+)
+
+func TestStore_conformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Store {
+		return sqlitestore.NewStore(sqlitestore.TestDBHelper(t))
+	})
+}
@@ -0,0 +1,35 @@
+package sqlitestore
+
+import (
+	"awesomeProject/internal/app/store"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/mattn/go-sqlite3"
+	"strings"
+)
+
+const (
+	usersEmailColumn    = "users.email"
+	usersUsernameColumn = "users.username"
+)
+
+// translateError maps sqlite errors to store errors, and cancelled statements to the context error.
+func translateError(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	if ctxErr := ctx.Err(); ctxErr != nil && !errors.Is(err, ctxErr) {
+		return fmt.Errorf("%w: %v", ctxErr, err)
+	}
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+		switch {
+		case strings.Contains(sqliteErr.Error(), usersEmailColumn):
+			return store.ErrEmailAlreadyExists
+		case strings.Contains(sqliteErr.Error(), usersUsernameColumn):
+			return store.ErrUsernameAlreadyExists
+		}
+	}
+	return err
+}
@@ -0,0 +1,101 @@
+package sqlitestore
+
+import (
+	"awesomeProject/internal/app/model"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+type IdempotencyRepository struct {
+	store *Store
+}
+
+func (r *IdempotencyRepository) Reserve(ctx context.Context, record *model.IdempotencyRecord) (*model.IdempotencyRecord, error) {
+	ctx, cancel := r.store.writeContext(ctx)
+	defer cancel()
+	// An expired record is taken over, a live one makes the statement return no row.
+	var reserved bool
+	err := r.store.conn.QueryRowContext(ctx,
+		"INSERT INTO idempotency_keys (scope, key, request_hash, created_at, expires_at) VALUES (?, ?, ?, ?, ?) "+
+			"ON CONFLICT (scope, key) DO UPDATE SET request_hash = excluded.request_hash, status = 0, headers = '{}', "+
+			"body = NULL, created_at = excluded.created_at, expires_at = excluded.expires_at "+
+			"WHERE idempotency_keys.expires_at <= excluded.created_at RETURNING true",
+		record.Scope,
+		record.Key,
+		record.RequestHash,
+		record.CreatedAt.UnixNano(),
+		record.ExpiresAt.UnixNano(),
+	).Scan(&reserved)
+	if err == nil {
+		return nil, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, translateError(ctx, err)
+	}
+
+	existing := &model.IdempotencyRecord{}
+	var headers []byte
+	var createdAt, expiresAt int64
+	err = r.store.conn.QueryRowContext(ctx,
+		"SELECT scope, key, request_hash, status, headers, body, created_at, expires_at "+
+			"FROM idempotency_keys WHERE scope = ? AND key = ?",
+		record.Scope,
+		record.Key,
+	).Scan(&existing.Scope, &existing.Key, &existing.RequestHash, &existing.Status, &headers, &existing.Body,
+		&createdAt, &expiresAt)
+	if err == sql.ErrNoRows {
+		// The record was released in between, the caller may try again.
+		return r.Reserve(ctx, record)
+	}
+	if err != nil {
+		return nil, translateError(ctx, err)
+	}
+	if err := json.Unmarshal(headers, &existing.Headers); err != nil {
+		return nil, err
+	}
+	existing.CreatedAt = time.Unix(0, createdAt)
+	existing.ExpiresAt = time.Unix(0, expiresAt)
+	return existing, nil
+}
+
+func (r *IdempotencyRepository) Complete(ctx context.Context, record *model.IdempotencyRecord) error {
+	ctx, cancel := r.store.writeContext(ctx)
+	defer cancel()
+	headers, err := json.Marshal(record.Headers)
+	if err != nil {
+		return err
+	}
+	_, err = r.store.conn.ExecContext(ctx,
+		"UPDATE idempotency_keys SET status = ?, headers = ?, body = ?, expires_at = ? WHERE scope = ? AND key = ?",
+		record.Status,
+		string(headers),
+		record.Body,
+		record.ExpiresAt.UnixNano(),
+		record.Scope,
+		record.Key,
+	)
+	return translateError(ctx, err)
+}
+
+func (r *IdempotencyRepository) Release(ctx context.Context, record *model.IdempotencyRecord) error {
+	ctx, cancel := r.store.writeContext(ctx)
+	defer cancel()
+	_, err := r.store.conn.ExecContext(ctx,
+		"DELETE FROM idempotency_keys WHERE scope = ? AND key = ? AND status = 0",
+		record.Scope,
+		record.Key,
+	)
+	return translateError(ctx, err)
+}
+
+func (r *IdempotencyRepository) DeleteExpired(ctx context.Context, at time.Time) (int64, error) {
+	ctx, cancel := r.store.writeContext(ctx)
+	defer cancel()
+	result, err := r.store.conn.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE expires_at <= ?", at.UnixNano())
+	if err != nil {
+		return 0, translateError(ctx, err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,12 @@
+package sqlitestore
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Migrations holds the SQL migrations applied to the sqlite database.
+var Migrations, _ = fs.Sub(migrationFiles, "migrations")
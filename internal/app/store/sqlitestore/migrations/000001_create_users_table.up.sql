@@ -0,0 +1,9 @@
+CREATE TABLE users
+(
+    id              integer not null primary key autoincrement,
+    email           text    not null unique,
+    password        text    not null,
+    role            text    not null DEFAULT 'basic' CHECK (role IN ('basic', 'admin', 'moderator')),
+    session_version integer not null DEFAULT 0,
+    version         integer not null DEFAULT 1
+);
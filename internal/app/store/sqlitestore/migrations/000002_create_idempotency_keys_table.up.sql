@@ -0,0 +1,15 @@
+CREATE TABLE idempotency_keys
+(
+    scope        text    not null,
+    key          text    not null,
+    request_hash text    not null,
+    status       integer not null DEFAULT 0,
+    headers      text    not null DEFAULT '{}',
+    body         blob,
+    -- Unix time in nanoseconds, sqlite has no timestamp type that compares reliably.
+    created_at   integer not null,
+    expires_at   integer not null,
+    PRIMARY KEY (scope, key)
+);
+
+CREATE INDEX idempotency_keys_expires_at_idx ON idempotency_keys (expires_at);
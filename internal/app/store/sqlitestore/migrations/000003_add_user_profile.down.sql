@@ -0,0 +1,6 @@
+DROP INDEX users_username_key;
+
+ALTER TABLE users DROP COLUMN display_name;
+ALTER TABLE users DROP COLUMN username;
+ALTER TABLE users DROP COLUMN locale;
+ALTER TABLE users DROP COLUMN timezone;
@@ -0,0 +1,6 @@
+ALTER TABLE users ADD COLUMN display_name text not null DEFAULT '';
+ALTER TABLE users ADD COLUMN username text not null DEFAULT '';
+ALTER TABLE users ADD COLUMN locale text not null DEFAULT 'en';
+ALTER TABLE users ADD COLUMN timezone text not null DEFAULT 'UTC';
+
+CREATE UNIQUE INDEX users_username_key ON users (username) WHERE username <> '';
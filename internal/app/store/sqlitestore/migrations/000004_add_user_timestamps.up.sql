@@ -0,0 +1,8 @@
+-- Times are unix nanoseconds, existing users get the time of the migration.
+ALTER TABLE users ADD COLUMN created_at integer not null DEFAULT 0;
+ALTER TABLE users ADD COLUMN updated_at integer not null DEFAULT 0;
+ALTER TABLE users ADD COLUMN last_login_at integer;
+
+UPDATE users
+SET created_at = strftime('%s', 'now') * 1000000000,
+    updated_at = strftime('%s', 'now') * 1000000000;
@@ -0,0 +1,141 @@
+package sqlitestore
+
+import (
+	"awesomeProject/internal/app/store"
+	"context"
+	"database/sql"
+	"fmt"
+	_ "github.com/mattn/go-sqlite3"
+	"strings"
+	"time"
+)
+
+const DriverName = "sqlite3"
+
+// defaultPragmas make writers wait for the lock instead of failing with "database is locked".
+var defaultPragmas = []string{"_busy_timeout=5000", "_txlock=immediate", "_journal_mode=WAL", "_foreign_keys=on"}
+
+// querier runs statements on the database or inside a transaction.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+type Store struct {
+	db *sql.DB
+	// conn is db, or the transaction of a store handed out by WithTx.
+	conn                  querier
+	inTx                  bool
+	readTimeout           time.Duration
+	writeTimeout          time.Duration
+	userRepository        *UserRepository
+	idempotencyRepository *IdempotencyRepository
+}
+
+// Open adds the default pragmas the data source name does not set.
+func Open(dataSourceName string) (*sql.DB, error) {
+	var missing []string
+	for _, pragma := range defaultPragmas {
+		if !strings.Contains(dataSourceName, strings.SplitN(pragma, "=", 2)[0]+"=") {
+			missing = append(missing, pragma)
+		}
+	}
+	if len(missing) > 0 {
+		separator := "?"
+		if strings.Contains(dataSourceName, "?") {
+			separator = "&"
+		}
+		dataSourceName = fmt.Sprintf("%s%s%s", dataSourceName, separator, strings.Join(missing, "&"))
+	}
+	return sql.Open(DriverName, dataSourceName)
+}
+
+// Option configures a Store created by NewStore.
+type Option func(s *Store)
+
+// WithTimeouts bounds read and write statements, zero leaves them unbounded.
+func WithTimeouts(read time.Duration, write time.Duration) Option {
+	return func(s *Store) {
+		s.readTimeout = read
+		s.writeTimeout = write
+	}
+}
+
+func NewStore(db *sql.DB, options ...Option) *Store {
+	s := &Store{
+		db:   db,
+		conn: db,
+	}
+	for _, option := range options {
+		option(s)
+	}
+	return s.initRepositories()
+}
+
+// initRepositories creates the repositories up front, so that the store is safe for concurrent use.
+func (s *Store) initRepositories() *Store {
+	s.userRepository = &UserRepository{
+		store: s,
+	}
+	s.idempotencyRepository = &IdempotencyRepository{
+		store: s,
+	}
+	return s
+}
+
+func (s *Store) UserRepository() store.UserRepository {
+	return s.userRepository
+}
+
+func (s *Store) IdempotencyRepository() store.IdempotencyRepository {
+	return s.idempotencyRepository
+}
+
+// WithTx runs fn in an immediate transaction, nested calls join it.
+func (s *Store) WithTx(ctx context.Context, fn func(store.Store) error) (err error) {
+	if s.inTx {
+		return fn(s)
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return translateError(ctx, err)
+	}
+
+	committed := false
+	defer func() {
+		if !committed {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil && err != nil {
+				err = fmt.Errorf("%w (rollback failed: %v)", err, rollbackErr)
+			}
+		}
+	}()
+
+	txStore := (&Store{
+		db:           s.db,
+		conn:         tx,
+		inTx:         true,
+		readTimeout:  s.readTimeout,
+		writeTimeout: s.writeTimeout,
+	}).initRepositories()
+	if err := fn(txStore); err != nil {
+		return err
+	}
+	committed = true
+	return translateError(ctx, tx.Commit())
+}
+
+func (s *Store) readContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return withTimeout(ctx, s.readTimeout)
+}
+
+func (s *Store) writeContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return withTimeout(ctx, s.writeTimeout)
+}
+
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
@@ -0,0 +1,31 @@
+package sqlitestore
+
+import (
+	"awesomeProject/internal/app/migrator"
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+// TestDBHelper opens a migrated database in a file removed with the test's temporary directory.
+func TestDBHelper(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := Open("file:" + filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	schema, err := migrator.NewWithDialect(db, Migrations, migrator.SQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := schema.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
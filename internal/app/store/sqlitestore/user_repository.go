@@ -0,0 +1,208 @@
+package sqlitestore
+
+import (
+	"awesomeProject/internal/app/model"
+	"awesomeProject/internal/app/store"
+	"context"
+	"database/sql"
+	"time"
+)
+
+// userColumns are scanned by scanUser, the password is left out of lists.
+const (
+	userColumns        = "id, email, password, role, session_version, version, " + userProfileColumns
+	userProfileColumns = "display_name, username, locale, timezone, avatar_version, created_at, updated_at, last_login_at"
+)
+
+type UserRepository struct {
+	store *Store
+}
+
+// scanUser reads the userColumns of a row.
+func scanUser(scan func(dest ...interface{}) error) (*model.User, error) {
+	user := model.NewEmptyUser()
+	var createdAt, updatedAt int64
+	var lastLoginAt sql.NullInt64
+	err := scan(&user.Id, &user.Email, &user.Password.Encrypted, &user.Role, &user.SessionVersion, &user.Version,
+		&user.DisplayName, &user.Username, &user.Locale, &user.Timezone, &user.AvatarVersion,
+		&createdAt, &updatedAt, &lastLoginAt)
+	if err != nil {
+		return nil, err
+	}
+	setTimes(user, createdAt, updatedAt, lastLoginAt)
+	return user, nil
+}
+
+// setTimes sets the times stored as unix nanoseconds.
+func setTimes(user *model.User, createdAt, updatedAt int64, lastLoginAt sql.NullInt64) {
+	user.CreatedAt = time.Unix(0, createdAt).UTC()
+	user.UpdatedAt = time.Unix(0, updatedAt).UTC()
+	user.LastLoginAt = nil
+	if lastLoginAt.Valid {
+		at := time.Unix(0, lastLoginAt.Int64).UTC()
+		user.LastLoginAt = &at
+	}
+}
+
+func (r *UserRepository) Create(ctx context.Context, user *model.User) error {
+	ctx, cancel := r.store.writeContext(ctx)
+	defer cancel()
+	err := user.BeforeCreateOrUpdate()
+	if err != nil {
+		return err
+	}
+	now := time.Now().UnixNano()
+	err = r.store.conn.QueryRowContext(ctx,
+		"INSERT INTO users (email, password, role, display_name, username, locale, timezone, avatar_version, "+
+			"created_at, updated_at) "+
+			"VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING id, session_version, version",
+		user.Email,
+		user.Password.Encrypted,
+		user.Role,
+		user.DisplayName,
+		user.Username,
+		user.Locale,
+		user.Timezone,
+		user.AvatarVersion,
+		now,
+		now,
+	).Scan(&user.Id, &user.SessionVersion, &user.Version)
+	if err != nil {
+		return translateError(ctx, err)
+	}
+	setTimes(user, now, now, sql.NullInt64{})
+	return nil
+}
+
+func (r *UserRepository) FindByEmail(ctx context.Context, email string) (*model.User, error) {
+	ctx, cancel := r.store.readContext(ctx)
+	defer cancel()
+	user, err := scanUser(r.store.conn.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE email = ?", email).Scan)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, store.ErrRecordNotFound
+		}
+		return nil, translateError(ctx, err)
+	}
+	return user, nil
+}
+
+func (r *UserRepository) FindById(ctx context.Context, id int) (*model.User, error) {
+	ctx, cancel := r.store.readContext(ctx)
+	defer cancel()
+	user, err := scanUser(r.store.conn.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE id = ?", id).Scan)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, store.ErrRecordNotFound
+		}
+		return nil, translateError(ctx, err)
+	}
+	return user, nil
+}
+
+func (r *UserRepository) AllUsers(ctx context.Context) ([]*model.User, error) {
+	ctx, cancel := r.store.readContext(ctx)
+	defer cancel()
+	rows, err := r.store.conn.QueryContext(ctx, "SELECT id, email, role, "+userProfileColumns+" FROM users")
+	if err != nil {
+		return nil, translateError(ctx, err)
+	}
+	defer rows.Close()
+
+	var users []*model.User
+	for rows.Next() {
+		user := &model.User{}
+		var createdAt, updatedAt int64
+		var lastLoginAt sql.NullInt64
+		if err := rows.Scan(&user.Id, &user.Email, &user.Role,
+			&user.DisplayName, &user.Username, &user.Locale, &user.Timezone, &user.AvatarVersion,
+			&createdAt, &updatedAt, &lastLoginAt); err != nil {
+			return nil, store.ErrDatabaseInternal
+		}
+		setTimes(user, createdAt, updatedAt, lastLoginAt)
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, translateError(ctx, err)
+	}
+	return users, nil
+}
+
+func (r *UserRepository) Update(ctx context.Context, user *model.User) error {
+	ctx, cancel := r.store.writeContext(ctx)
+	defer cancel()
+	err := user.BeforeCreateOrUpdate()
+	if err != nil {
+		return err
+	}
+	var createdAt, updatedAt int64
+	var lastLoginAt sql.NullInt64
+	err = r.store.conn.QueryRowContext(ctx,
+		"UPDATE users SET email = ?, password = ?, role = ?, session_version = ?, "+
+			"display_name = ?, username = ?, locale = ?, timezone = ?, avatar_version = ?, updated_at = ?, "+
+			"version = version + 1 "+
+			"WHERE id = ? AND version = ? RETURNING version, created_at, updated_at, last_login_at",
+		user.Email,
+		user.Password.Encrypted,
+		user.Role,
+		user.SessionVersion,
+		user.DisplayName,
+		user.Username,
+		user.Locale,
+		user.Timezone,
+		user.AvatarVersion,
+		time.Now().UnixNano(),
+		user.Id,
+		user.Version,
+	).Scan(&user.Version, &createdAt, &updatedAt, &lastLoginAt)
+	if err == sql.ErrNoRows {
+		return r.missingOrConflict(ctx, user.Id)
+	}
+	if err != nil {
+		return translateError(ctx, err)
+	}
+	setTimes(user, createdAt, updatedAt, lastLoginAt)
+	return nil
+}
+
+func (r *UserRepository) RecordLogin(ctx context.Context, user *model.User) error {
+	ctx, cancel := r.store.writeContext(ctx)
+	defer cancel()
+	now := time.Now().UnixNano()
+	result, err := r.store.conn.ExecContext(ctx, "UPDATE users SET last_login_at = ? WHERE id = ?", now, user.Id)
+	if err != nil {
+		return translateError(ctx, err)
+	}
+	if updated, err := result.RowsAffected(); err != nil || updated == 0 {
+		return store.ErrRecordNotFound
+	}
+	at := time.Unix(0, now).UTC()
+	user.LastLoginAt = &at
+	return nil
+}
+
+func (r *UserRepository) Delete(ctx context.Context, user *model.User) error {
+	ctx, cancel := r.store.writeContext(ctx)
+	defer cancel()
+	result, err := r.store.conn.ExecContext(ctx, "DELETE FROM users WHERE id = ? AND version = ?", user.Id, user.Version)
+	if err != nil {
+		return translateError(ctx, err)
+	}
+	if deleted, err := result.RowsAffected(); err != nil || deleted == 0 {
+		return r.missingOrConflict(ctx, user.Id)
+	}
+	return nil
+}
+
+// missingOrConflict tells a deleted user from a newer version.
+func (r *UserRepository) missingOrConflict(ctx context.Context, id int) error {
+	var exist bool
+	err := r.store.conn.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM users WHERE id = ?)", id).Scan(&exist)
+	if err != nil {
+		return translateError(ctx, err)
+	}
+	if !exist {
+		return store.ErrRecordNotFound
+	}
+	return store.ErrVersionConflict
+}
@@ -0,0 +1,156 @@
+package sqlitestore_test
+
+import (
+	"awesomeProject/internal/app/store"
+	"awesomeProject/internal/app/store/sqlitestore"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestUserRepository_Create(t *testing.T) {
+	db := sqlitestore.TestDBHelper(t)
+
+	s := sqlitestore.NewStore(db)
+	userGen := store.TestUserHelper(t)
+	user := userGen()
+	err := s.UserRepository().Create(context.Background(), user)
+	assert.NoError(t, err)
+	assert.NotNil(t, user)
+}
+
+func TestUserRepository_FindByEmail(t *testing.T) {
+	db := sqlitestore.TestDBHelper(t)
+
+	s := sqlitestore.NewStore(db)
+
+	email := "abc@gmail.com"
+
+	user, err := s.UserRepository().FindByEmail(context.Background(), email)
+	assert.EqualError(t, err, store.ErrRecordNotFound.Error())
+
+	userGen := store.TestUserHelper(t)
+	user = userGen()
+	err = s.UserRepository().Create(context.Background(), user)
+	assert.NoError(t, err)
+	assert.NotNil(t, user)
+
+	user, err = s.UserRepository().FindByEmail(context.Background(), email)
+	assert.NoError(t, err)
+	assert.Equal(t, email, user.Email)
+}
+
+func TestUserRepository_FindById(t *testing.T) {
+	db := sqlitestore.TestDBHelper(t)
+
+	s := sqlitestore.NewStore(db)
+	userGen := store.TestUserHelper(t)
+	user := userGen()
+	err := s.UserRepository().Create(context.Background(), user)
+	assert.NoError(t, err)
+	assert.NotNil(t, user)
+
+	returnedUser, err := s.UserRepository().FindById(context.Background(), user.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, returnedUser.Id, user.Id)
+}
+
+func TestUserRepository_AllUsers(t *testing.T) {
+	db := sqlitestore.TestDBHelper(t)
+
+	s := sqlitestore.NewStore(db)
+	userGen := store.TestUserHelper(t, 1, "abcabcabc@mail.com", "1234567890")
+	user := userGen()
+	err := s.UserRepository().Create(context.Background(), user)
+	assert.NoError(t, err)
+	assert.NotNil(t, user)
+
+	userGen = store.TestUserHelper(t, 2, "abcabc@mail.com", "1234567890")
+	user = userGen()
+	err = s.UserRepository().Create(context.Background(), user)
+
+	users, err := s.UserRepository().AllUsers(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(users))
+}
+
+func TestUserRepository_Update(t *testing.T) {
+	db := sqlitestore.TestDBHelper(t)
+
+	s := sqlitestore.NewStore(db)
+	userGen := store.TestUserHelper(t)
+	user := userGen()
+	err := s.UserRepository().Create(context.Background(), user)
+	assert.NoError(t, err)
+	assert.NotNil(t, user)
+
+	newEmail := "abababa@mail.com"
+	user.Email = newEmail
+	err = s.UserRepository().Update(context.Background(), user)
+	assert.NoError(t, err)
+	assert.Equal(t, newEmail, user.Email)
+}
+
+func TestUserRepository_Delete(t *testing.T) {
+	db := sqlitestore.TestDBHelper(t)
+
+	s := sqlitestore.NewStore(db)
+	userGen := store.TestUserHelper(t)
+	user := userGen()
+	err := s.UserRepository().Create(context.Background(), user)
+	assert.NoError(t, err)
+	assert.NotNil(t, user)
+
+	err = s.UserRepository().Delete(context.Background(), user)
+	assert.NoError(t, err)
+
+	users, err := s.UserRepository().AllUsers(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(users))
+}
+
+func TestUserRepository_StaleVersion(t *testing.T) {
+	db := sqlitestore.TestDBHelper(t)
+
+	s := sqlitestore.NewStore(db)
+	userGen := store.TestUserHelper(t)
+	user := userGen()
+	err := s.UserRepository().Create(context.Background(), user)
+	assert.NoError(t, err)
+
+	stale := *user
+	user.Email = "abababa@mail.com"
+	err = s.UserRepository().Update(context.Background(), user)
+	assert.NoError(t, err)
+	assert.Equal(t, stale.Version+1, user.Version)
+
+	stale.Email = "bababab@mail.com"
+	assert.ErrorIs(t, s.UserRepository().Update(context.Background(), &stale), store.ErrVersionConflict)
+	assert.ErrorIs(t, s.UserRepository().Delete(context.Background(), &stale), store.ErrVersionConflict)
+}
+
+func TestUserRepository_Create_duplicateEmail(t *testing.T) {
+	db := sqlitestore.TestDBHelper(t)
+
+	s := sqlitestore.NewStore(db)
+	userGen := store.TestUserHelper(t)
+	assert.NoError(t, s.UserRepository().Create(context.Background(), userGen()))
+	err := s.UserRepository().Create(context.Background(), userGen())
+	assert.ErrorIs(t, err, store.ErrEmailAlreadyExists)
+}
+
+func TestStore_WithTimeouts(t *testing.T) {
+	db := sqlitestore.TestDBHelper(t)
+	user := store.TestUserHelper(t)()
+	assert.NoError(t, sqlitestore.NewStore(db).UserRepository().Create(context.Background(), user))
+
+	s := sqlitestore.NewStore(db, sqlitestore.WithTimeouts(0, time.Nanosecond))
+	_, err := s.UserRepository().FindById(context.Background(), user.Id)
+	assert.NoError(t, err)
+	assert.ErrorIs(t, s.UserRepository().Update(context.Background(), user), context.DeadlineExceeded)
+
+	s = sqlitestore.NewStore(db, sqlitestore.WithTimeouts(time.Nanosecond, 0))
+	_, err = s.UserRepository().FindById(context.Background(), user.Id)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
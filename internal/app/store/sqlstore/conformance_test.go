@@ -0,0 +1,18 @@
+package sqlstore_test
+
+import (
+	"awesomeProject/internal/app/store"
+	"awesomeProject/internal/app/store/sqlstore"
+	"awesomeProject/internal/app/store/storetest"
+	"testing"
+)
+
+func TestStore_conformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Store {
+		db, teardown := sqlstore.TestDBHelper(t, false)
+		t.Cleanup(func() {
+			teardown("users", "idempotency_keys")
+		})
+		return sqlstore.NewStore(db)
+	})
+}
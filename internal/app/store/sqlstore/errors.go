@@ -0,0 +1,36 @@
+package sqlstore
+
+import (
+	"awesomeProject/internal/app/store"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+)
+
+const (
+	uniqueViolationCode  = "23505"
+	usersEmailConstraint = "users_email_key"
+	// usersUsernameConstraint is a unique index, postgres reports its name as the constraint.
+	usersUsernameConstraint = "users_username_key"
+)
+
+// translateError maps postgres errors to store errors, and cancelled statements to the context error.
+func translateError(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	if ctxErr := ctx.Err(); ctxErr != nil && !errors.Is(err, ctxErr) {
+		return fmt.Errorf("%w: %v", ctxErr, err)
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolationCode {
+		switch pqErr.Constraint {
+		case usersEmailConstraint:
+			return store.ErrEmailAlreadyExists
+		case usersUsernameConstraint:
+			return store.ErrUsernameAlreadyExists
+		}
+	}
+	return err
+}
@@ -0,0 +1,99 @@
+package sqlstore
+
+import (
+	"awesomeProject/internal/app/model"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+type IdempotencyRepository struct {
+	store *Store
+}
+
+func (r *IdempotencyRepository) Reserve(ctx context.Context, record *model.IdempotencyRecord) (*model.IdempotencyRecord, error) {
+	ctx, cancel := r.store.writeContext(ctx)
+	defer cancel()
+
+	// An expired record is taken over, a live one makes the statement return no row.
+	var reserved bool
+	err := r.store.queryRow(ctx,
+		"INSERT INTO idempotency_keys (scope, key, request_hash, created_at, expires_at) VALUES ($1, $2, $3, $4, $5) "+
+			"ON CONFLICT (scope, key) DO UPDATE SET request_hash = EXCLUDED.request_hash, status = 0, headers = '{}', "+
+			"body = NULL, created_at = EXCLUDED.created_at, expires_at = EXCLUDED.expires_at "+
+			"WHERE idempotency_keys.expires_at <= EXCLUDED.created_at RETURNING true",
+		record.Scope,
+		record.Key,
+		record.RequestHash,
+		record.CreatedAt,
+		record.ExpiresAt,
+	).Scan(&reserved)
+	if err == nil {
+		return nil, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, translateError(ctx, err)
+	}
+
+	existing := &model.IdempotencyRecord{}
+	var headers []byte
+	err = r.store.queryRow(ctx,
+		"SELECT scope, key, request_hash, status, headers, body, created_at, expires_at "+
+			"FROM idempotency_keys WHERE scope = $1 AND key = $2",
+		record.Scope,
+		record.Key,
+	).Scan(&existing.Scope, &existing.Key, &existing.RequestHash, &existing.Status, &headers, &existing.Body,
+		&existing.CreatedAt, &existing.ExpiresAt)
+	if err == sql.ErrNoRows {
+		// The record was released in between, the caller may try again.
+		return r.Reserve(ctx, record)
+	}
+	if err != nil {
+		return nil, translateError(ctx, err)
+	}
+	if err := json.Unmarshal(headers, &existing.Headers); err != nil {
+		return nil, err
+	}
+	return existing, nil
+}
+
+func (r *IdempotencyRepository) Complete(ctx context.Context, record *model.IdempotencyRecord) error {
+	headers, err := json.Marshal(record.Headers)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := r.store.writeContext(ctx)
+	defer cancel()
+	_, err = r.store.exec(ctx,
+		"UPDATE idempotency_keys SET status = $3, headers = $4, body = $5, expires_at = $6 WHERE scope = $1 AND key = $2",
+		record.Scope,
+		record.Key,
+		record.Status,
+		headers,
+		record.Body,
+		record.ExpiresAt,
+	)
+	return translateError(ctx, err)
+}
+
+func (r *IdempotencyRepository) Release(ctx context.Context, record *model.IdempotencyRecord) error {
+	ctx, cancel := r.store.writeContext(ctx)
+	defer cancel()
+	_, err := r.store.exec(ctx,
+		"DELETE FROM idempotency_keys WHERE scope = $1 AND key = $2 AND status = 0",
+		record.Scope,
+		record.Key,
+	)
+	return translateError(ctx, err)
+}
+
+func (r *IdempotencyRepository) DeleteExpired(ctx context.Context, at time.Time) (int64, error) {
+	ctx, cancel := r.store.writeContext(ctx)
+	defer cancel()
+	result, err := r.store.exec(ctx, "DELETE FROM idempotency_keys WHERE expires_at <= $1", at)
+	if err != nil {
+		return 0, translateError(ctx, err)
+	}
+	return result.RowsAffected()
+}
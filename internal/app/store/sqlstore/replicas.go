@@ -0,0 +1,113 @@
+package sqlstore
+
+import (
+	"awesomeProject/internal/app/store"
+	"context"
+	"database/sql"
+	"log"
+	"sync"
+	"time"
+)
+
+// replicaRetryInterval is how long reads skip a replica after it failed.
+const replicaRetryInterval = 5 * time.Second
+
+type replica struct {
+	db         *sql.DB
+	statements *statements
+
+	mutex     sync.Mutex
+	downUntil time.Time
+}
+
+// WithReplicas spreads reads that may be stale over the replicas, failing ones are skipped for a while.
+func WithReplicas(replicas ...*sql.DB) Option {
+	return func(s *Store) {
+		for _, db := range replicas {
+			s.replicas = append(s.replicas, &replica{
+				db:         db,
+				statements: newStatements(db),
+			})
+		}
+	}
+}
+
+func (r *replica) available(now time.Time) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return !now.Before(r.downUntil)
+}
+
+// failed takes the replica out of rotation when err is not a query result.
+func (r *replica) failed(ctx context.Context, err error) bool {
+	if err == nil || err == sql.ErrNoRows || ctx.Err() != nil {
+		return false
+	}
+	log.Printf("Read replica failed, reading from the primary: %v", err)
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.downUntil = time.Now().Add(replicaRetryInterval)
+	return true
+}
+
+// replica returns nil when the read must go to the primary.
+func (s *Store) replica(ctx context.Context) *replica {
+	if s.tx != nil || len(s.replicas) == 0 || store.UsesPrimary(ctx) {
+		return nil
+	}
+	now := time.Now()
+	start := int(s.nextReplica.Add(1))
+	for i := 0; i < len(s.replicas); i++ {
+		candidate := s.replicas[(start+i)%len(s.replicas)]
+		if candidate.available(now) {
+			return candidate
+		}
+	}
+	return nil
+}
+
+// readRow is a row read from a replica, or from the primary when the replica fails.
+type readRow struct {
+	store *Store
+	ctx   context.Context
+	query string
+	args  []interface{}
+}
+
+func (r *readRow) Scan(dest ...interface{}) error {
+	if replica := r.store.replica(r.ctx); replica != nil {
+		err := func() error {
+			stmt, err := replica.statements.get(r.ctx, r.query)
+			if err != nil {
+				return err
+			}
+			return stmt.QueryRowContext(r.ctx, r.args...).Scan(dest...)
+		}()
+		if !replica.failed(r.ctx, err) {
+			return err
+		}
+	}
+	return r.store.queryRow(r.ctx, r.query, r.args...).Scan(dest...)
+}
+
+func (s *Store) readRow(ctx context.Context, query string, args ...interface{}) *readRow {
+	return &readRow{store: s, ctx: ctx, query: query, args: args}
+}
+
+func (s *Store) readQuery(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if replica := s.replica(ctx); replica != nil {
+		rows, err := func() (*sql.Rows, error) {
+			stmt, err := replica.statements.get(ctx, query)
+			if err != nil {
+				return nil, err
+			}
+			return stmt.QueryContext(ctx, args...)
+		}()
+		if !replica.failed(ctx, err) {
+			return rows, err
+		}
+	}
+	return s.query(ctx, query, args...)
+}
@@ -0,0 +1,37 @@
+package sqlstore_test
+
+import (
+	"awesomeProject/internal/app/store"
+	"awesomeProject/internal/app/store/sqlstore"
+	"context"
+	"database/sql"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestStore_WithReplicas_failover(t *testing.T) {
+	db, teardown := sqlstore.TestDBHelper(t, false)
+	defer teardown("users")
+
+	replica, err := sql.Open("postgres", "host=localhost port=1 dbname=unreachable connect_timeout=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer replica.Close()
+
+	s := sqlstore.NewStore(db, sqlstore.WithReplicas(replica))
+	userGen := store.TestUserHelper(t)
+	user := userGen()
+	assert.NoError(t, s.UserRepository().Create(context.Background(), user))
+
+	returnedUser, err := s.UserRepository().FindById(context.Background(), user.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, user.Id, returnedUser.Id)
+
+	users, err := s.UserRepository().AllUsers(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, users, 1)
+
+	_, err = s.UserRepository().FindByEmail(store.WithPrimary(context.Background()), "missing@mail.com")
+	assert.ErrorIs(t, err, store.ErrRecordNotFound)
+}
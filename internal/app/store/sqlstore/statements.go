@@ -0,0 +1,128 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+)
+
+// errStoreClosed is returned by statements used after Store.Close.
+var errStoreClosed = errors.New("sqlstore: store is closed")
+
+// statements prepares each query once and is shared with the stores of WithTx.
+type statements struct {
+	db *sql.DB
+
+	mutex    sync.Mutex
+	prepared map[string]*sql.Stmt
+	closed   bool
+}
+
+func newStatements(db *sql.DB) *statements {
+	return &statements{
+		db:       db,
+		prepared: make(map[string]*sql.Stmt),
+	}
+}
+
+// get prepares query outside the lock, so a slow prepare does not hold up other queries.
+func (s *statements) get(ctx context.Context, query string) (*sql.Stmt, error) {
+	if stmt, exist, err := s.lookup(query); exist || err != nil {
+		return stmt, err
+	}
+	stmt, err := s.db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.closed {
+		_ = stmt.Close()
+		return nil, errStoreClosed
+	}
+	if prepared, exist := s.prepared[query]; exist {
+		_ = stmt.Close()
+		return prepared, nil
+	}
+	s.prepared[query] = stmt
+	return stmt, nil
+}
+
+func (s *statements) lookup(query string) (*sql.Stmt, bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.closed {
+		return nil, false, errStoreClosed
+	}
+	stmt, exist := s.prepared[query]
+	return stmt, exist, nil
+}
+
+// close closes the prepared statements and returns the first error.
+func (s *statements) close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.closed = true
+	var firstErr error
+	for query, stmt := range s.prepared {
+		if err := stmt.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		delete(s.prepared, query)
+	}
+	return firstErr
+}
+
+// row is the result of queryRow, it reports the error of preparing the statement when scanned.
+type row struct {
+	*sql.Row
+	err error
+}
+
+func (r *row) Scan(dest ...interface{}) error {
+	if r.err != nil {
+		return r.err
+	}
+	return r.Row.Scan(dest...)
+}
+
+// stmt returns the prepared statement of query, bound to the transaction of the store if it has one.
+func (s *Store) stmt(ctx context.Context, query string) (*sql.Stmt, error) {
+	stmt, err := s.statements.get(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if s.tx != nil {
+		return s.tx.StmtContext(ctx, stmt), nil
+	}
+	return stmt, nil
+}
+
+func (s *Store) exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	stmt, err := s.stmt(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return stmt.ExecContext(ctx, args...)
+}
+
+func (s *Store) query(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	stmt, err := s.stmt(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return stmt.QueryContext(ctx, args...)
+}
+
+func (s *Store) queryRow(ctx context.Context, query string, args ...interface{}) *row {
+	stmt, err := s.stmt(ctx, query)
+	if err != nil {
+		return &row{err: err}
+	}
+	return &row{Row: stmt.QueryRowContext(ctx, args...)}
+}
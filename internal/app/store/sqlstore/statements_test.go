@@ -0,0 +1,60 @@
+package sqlstore_test
+
+import (
+	"awesomeProject/internal/app/store"
+	"awesomeProject/internal/app/store/sqlstore"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+)
+
+func TestStore_concurrentStatements(t *testing.T) {
+	db, teardown := sqlstore.TestDBHelper(t, false)
+	defer teardown("users")
+
+	s := sqlstore.NewStore(db)
+	defer s.Close()
+	user := store.TestUserHelper(t)()
+	require.NoError(t, s.UserRepository().Create(context.Background(), user))
+
+	// A fresh store prepares each query once, whichever of the concurrent callers gets there first.
+	s = sqlstore.NewStore(db)
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := s.UserRepository().FindById(context.Background(), user.Id)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, s.Close())
+}
+
+func TestStore_Close(t *testing.T) {
+	db, teardown := sqlstore.TestDBHelper(t, false)
+	defer teardown("users")
+
+	s := sqlstore.NewStore(db)
+	user := store.TestUserHelper(t)()
+	require.NoError(t, s.UserRepository().Create(context.Background(), user))
+	_, err := s.UserRepository().FindById(context.Background(), user.Id)
+	require.NoError(t, err)
+
+	assert.NoError(t, s.Close())
+	_, err = s.UserRepository().FindById(context.Background(), user.Id)
+	assert.Error(t, err)
+	assert.NoError(t, s.Close())
+
+	// The pool stays open for the stores sharing it.
+	_, err = sqlstore.NewStore(db).UserRepository().FindById(context.Background(), user.Id)
+	assert.NoError(t, err)
+}
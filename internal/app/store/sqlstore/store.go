@@ -2,26 +2,91 @@ package sqlstore
 
 import (
 	"awesomeProject/internal/app/store"
+	"context"
 	"database/sql"
 	_ "github.com/lib/pq"
+	"sync/atomic"
+	"time"
 )
 
 type Store struct {
-	db             *sql.DB
-	userRepository *UserRepository
+	db *sql.DB
+	// tx is the transaction of a store handed out by WithTx.
+	tx                    *sql.Tx
+	statements            *statements
+	replicas              []*replica
+	nextReplica           atomic.Uint32
+	readTimeout           time.Duration
+	writeTimeout          time.Duration
+	txIsolation           sql.IsolationLevel
+	txRetries             int
+	userRepository        *UserRepository
+	idempotencyRepository *IdempotencyRepository
 }
 
-func NewStore(db *sql.DB) *Store {
-	return &Store{
-		db: db,
+// Option configures a Store created by NewStore.
+type Option func(s *Store)
+
+// WithTimeouts bounds read and write statements, zero leaves them unbounded.
+func WithTimeouts(read time.Duration, write time.Duration) Option {
+	return func(s *Store) {
+		s.readTimeout = read
+		s.writeTimeout = write
 	}
 }
 
-func (s *Store) UserRepository() store.UserRepository {
-	if s.userRepository == nil {
-		s.userRepository = &UserRepository{
-			store: s,
+func NewStore(db *sql.DB, options ...Option) *Store {
+	s := &Store{
+		db:         db,
+		statements: newStatements(db),
+	}
+	for _, option := range options {
+		option(s)
+	}
+	return s.initRepositories()
+}
+
+// initRepositories creates the repositories up front, so that the store is safe for concurrent use.
+func (s *Store) initRepositories() *Store {
+	s.userRepository = &UserRepository{
+		store: s,
+	}
+	s.idempotencyRepository = &IdempotencyRepository{
+		store: s,
+	}
+	return s
+}
+
+// Close closes the prepared statements of the primary and the replicas, the pools are left open.
+func (s *Store) Close() error {
+	err := s.statements.close()
+	for _, replica := range s.replicas {
+		if replicaErr := replica.statements.close(); replicaErr != nil && err == nil {
+			err = replicaErr
 		}
 	}
+	return err
+}
+
+func (s *Store) UserRepository() store.UserRepository {
 	return s.userRepository
 }
+
+func (s *Store) IdempotencyRepository() store.IdempotencyRepository {
+	return s.idempotencyRepository
+}
+
+func (s *Store) readContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return withTimeout(ctx, s.readTimeout)
+}
+
+func (s *Store) writeContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return withTimeout(ctx, s.writeTimeout)
+}
+
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
@@ -1,6 +1,9 @@
 package sqlstore
 
 import (
+	"awesomeProject/internal/app/migrator"
+	"awesomeProject/migrations"
+	"context"
 	"database/sql"
 	"fmt"
 	"os"
@@ -33,6 +36,14 @@ func TestDBHelper(t *testing.T, readEnv bool) (*sql.DB, func(...string)) {
 		t.Fatal(err)
 	}
 
+	schema, err := migrator.New(db, migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := schema.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
 	return db, func(tables ...string) {
 		if len(tables) > 0 {
 			_, err := db.Exec(fmt.Sprintf("TRUNCATE %s CASCADE", strings.Join(tables, ", ")))
@@ -0,0 +1,70 @@
+package sqlstore
+
+import (
+	"awesomeProject/internal/app/store"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+)
+
+const (
+	serializationFailureCode = "40001"
+	deadlockDetectedCode     = "40P01"
+)
+
+// WithTxIsolation sets the isolation of WithTx and how often serialization failures are retried.
+func WithTxIsolation(level sql.IsolationLevel, retries int) Option {
+	return func(s *Store) {
+		s.txIsolation = level
+		s.txRetries = retries
+	}
+}
+
+// WithTx runs fn in a transaction, nested calls join it. fn may run again after a serialization failure.
+func (s *Store) WithTx(ctx context.Context, fn func(store.Store) error) error {
+	if s.tx != nil {
+		return fn(s)
+	}
+	for attempt := 0; ; attempt++ {
+		err := s.runTx(ctx, fn)
+		if err == nil || attempt >= s.txRetries || !isRetryable(err) || ctx.Err() != nil {
+			return err
+		}
+	}
+}
+
+func (s *Store) runTx(ctx context.Context, fn func(store.Store) error) (err error) {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{Isolation: s.txIsolation})
+	if err != nil {
+		return translateError(ctx, err)
+	}
+	txStore := (&Store{
+		db:           s.db,
+		tx:           tx,
+		statements:   s.statements,
+		readTimeout:  s.readTimeout,
+		writeTimeout: s.writeTimeout,
+	}).initRepositories()
+
+	committed := false
+	defer func() {
+		if !committed {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil && err != nil {
+				err = fmt.Errorf("%w (rollback failed: %v)", err, rollbackErr)
+			}
+		}
+	}()
+
+	if err := fn(txStore); err != nil {
+		return err
+	}
+	committed = true
+	return translateError(ctx, tx.Commit())
+}
+
+func isRetryable(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && (pqErr.Code == serializationFailureCode || pqErr.Code == deadlockDetectedCode)
+}
@@ -0,0 +1,80 @@
+package sqlstore_test
+
+import (
+	"awesomeProject/internal/app/store"
+	"awesomeProject/internal/app/store/sqlstore"
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestStore_WithTx(t *testing.T) {
+	db, teardown := sqlstore.TestDBHelper(t, false)
+	defer teardown("users")
+
+	s := sqlstore.NewStore(db)
+	userGen := store.TestUserHelper(t)
+	user := userGen()
+
+	err := s.WithTx(context.Background(), func(tx store.Store) error {
+		if err := tx.UserRepository().Create(context.Background(), user); err != nil {
+			return err
+		}
+		_, err := tx.UserRepository().FindById(context.Background(), user.Id)
+		return err
+	})
+	assert.NoError(t, err)
+
+	_, err = s.UserRepository().FindById(context.Background(), user.Id)
+	assert.NoError(t, err)
+}
+
+func TestStore_WithTx_rollback(t *testing.T) {
+	db, teardown := sqlstore.TestDBHelper(t, false)
+	defer teardown("users")
+
+	errFailed := errors.New("failed")
+	s := sqlstore.NewStore(db)
+	userGen := store.TestUserHelper(t)
+
+	err := s.WithTx(context.Background(), func(tx store.Store) error {
+		if err := tx.UserRepository().Create(context.Background(), userGen()); err != nil {
+			return err
+		}
+		return errFailed
+	})
+	assert.ErrorIs(t, err, errFailed)
+
+	assert.Panics(t, func() {
+		_ = s.WithTx(context.Background(), func(tx store.Store) error {
+			if err := tx.UserRepository().Create(context.Background(), userGen()); err != nil {
+				return err
+			}
+			panic("failed")
+		})
+	})
+
+	users, err := s.UserRepository().AllUsers(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, users)
+}
+
+func TestStore_WithTx_retriesSerializationFailures(t *testing.T) {
+	db, teardown := sqlstore.TestDBHelper(t, false)
+	defer teardown("users")
+
+	s := sqlstore.NewStore(db, sqlstore.WithTxIsolation(sql.LevelSerializable, 2))
+	attempts := 0
+	err := s.WithTx(context.Background(), func(tx store.Store) error {
+		attempts++
+		if attempts == 1 {
+			_, err := db.Exec("DO $$ BEGIN RAISE EXCEPTION 'conflict' USING ERRCODE = 'serialization_failure'; END $$")
+			return err
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, attempts)
+}
@@ -0,0 +1,77 @@
+package sqlstore
+
+import (
+	"context"
+	"github.com/lib/pq"
+	"strconv"
+	"time"
+)
+
+const (
+	// usersChangedChannel is notified with the id of every updated or deleted user by a trigger on users.
+	usersChangedChannel = "users_changed"
+
+	listenerMinReconnectInterval = time.Second
+	listenerMaxReconnectInterval = time.Minute
+	// ListenerPingInterval is how often a UserChangeListener checks its connection and beats.
+	ListenerPingInterval = 90 * time.Second
+)
+
+// UserCache is the cache kept up to date by a UserChangeListener.
+type UserCache interface {
+	Invalidate(id int)
+	InvalidateAll()
+}
+
+// Heartbeat is told whether a UserChangeListener still receives notifications.
+type Heartbeat interface {
+	Beat()
+	Fail(err error)
+}
+
+// UserChangeListener invalidates cached users when any process changes them in the database.
+type UserChangeListener struct {
+	listener *pq.Listener
+}
+
+func NewUserChangeListener(databaseUrl string) (*UserChangeListener, error) {
+	listener := pq.NewListener(databaseUrl, listenerMinReconnectInterval, listenerMaxReconnectInterval, nil)
+	if err := listener.Listen(usersChangedChannel); err != nil {
+		_ = listener.Close()
+		return nil, err
+	}
+	return &UserChangeListener{listener: listener}, nil
+}
+
+// Run applies notifications until ctx is done and clears the cache after reconnecting.
+func (l *UserChangeListener) Run(ctx context.Context, cache UserCache, heartbeat Heartbeat) {
+	defer l.listener.Close()
+	ticker := time.NewTicker(ListenerPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case notification := <-l.listener.Notify:
+			if notification == nil {
+				cache.InvalidateAll()
+				continue
+			}
+			id, err := strconv.Atoi(notification.Extra)
+			if err != nil {
+				cache.InvalidateAll()
+				continue
+			}
+			cache.Invalidate(id)
+		case <-ticker.C:
+			if err := l.listener.Ping(); err != nil {
+				// Notifications may be lost until the listener reconnects.
+				cache.InvalidateAll()
+				heartbeat.Fail(err)
+				continue
+			}
+			heartbeat.Beat()
+		}
+	}
+}
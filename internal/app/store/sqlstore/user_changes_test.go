@@ -0,0 +1,65 @@
+package sqlstore_test
+
+import (
+	"awesomeProject/internal/app/store"
+	"awesomeProject/internal/app/store/sqlstore"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordingCache struct {
+	mutex       sync.Mutex
+	invalidated []int
+}
+
+func (c *recordingCache) Invalidate(id int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.invalidated = append(c.invalidated, id)
+}
+
+func (c *recordingCache) InvalidateAll() {}
+
+func (c *recordingCache) contains(id int) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for _, invalidated := range c.invalidated {
+		if invalidated == id {
+			return true
+		}
+	}
+	return false
+}
+
+type nopHeartbeat struct{}
+
+func (nopHeartbeat) Beat()      {}
+func (nopHeartbeat) Fail(error) {}
+
+func TestUserChangeListener(t *testing.T) {
+	db, teardown := sqlstore.TestDBHelper(t, false)
+	defer teardown("users")
+
+	listener, err := sqlstore.NewUserChangeListener("host=localhost port=5432 user=andrvat password=1234 dbname=awesome_project_dev_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache := &recordingCache{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go listener.Run(ctx, cache, nopHeartbeat{})
+
+	s := sqlstore.NewStore(db)
+	userGen := store.TestUserHelper(t)
+	user := userGen()
+	assert.NoError(t, s.UserRepository().Create(context.Background(), user))
+	user.Email = "new@mail.com"
+	assert.NoError(t, s.UserRepository().Update(context.Background(), user))
+
+	assert.Eventually(t, func() bool {
+		return cache.contains(user.Id)
+	}, 5*time.Second, 10*time.Millisecond)
+}
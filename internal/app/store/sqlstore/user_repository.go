@@ -3,61 +3,108 @@ package sqlstore
 import (
 	"awesomeProject/internal/app/model"
 	"awesomeProject/internal/app/store"
+	"context"
 	"database/sql"
 	"log"
+	"time"
+)
+
+// userColumns are scanned by scanUser, the password is left out of lists.
+const (
+	userColumns        = "id, email, password, role, session_version, version, " + userProfileColumns
+	userProfileColumns = "display_name, username, locale, timezone, avatar_version, created_at, updated_at, last_login_at"
 )
 
 type UserRepository struct {
 	store *Store
 }
 
-func (r *UserRepository) Create(user *model.User) error {
+// scanUser reads the userColumns of a row.
+func scanUser(scan func(dest ...interface{}) error) (*model.User, error) {
+	user := model.NewEmptyUser()
+	var lastLoginAt sql.NullTime
+	err := scan(&user.Id, &user.Email, &user.Password.Encrypted, &user.Role, &user.SessionVersion, &user.Version,
+		&user.DisplayName, &user.Username, &user.Locale, &user.Timezone, &user.AvatarVersion,
+		&user.CreatedAt, &user.UpdatedAt, &lastLoginAt)
+	if err != nil {
+		return nil, err
+	}
+	setTimes(user, user.CreatedAt, user.UpdatedAt, lastLoginAt)
+	return user, nil
+}
+
+// setTimes sets the times read from postgres in UTC, whatever the time zone of the session.
+func setTimes(user *model.User, createdAt, updatedAt time.Time, lastLoginAt sql.NullTime) {
+	user.CreatedAt = createdAt.UTC()
+	user.UpdatedAt = updatedAt.UTC()
+	user.LastLoginAt = nil
+	if lastLoginAt.Valid {
+		at := lastLoginAt.Time.UTC()
+		user.LastLoginAt = &at
+	}
+}
+
+func (r *UserRepository) Create(ctx context.Context, user *model.User) error {
 	err := user.BeforeCreateOrUpdate()
 	if err != nil {
 		return err
 	}
-	err = r.store.db.QueryRow(
-		"INSERT INTO users (email, password) VALUES ($1, $2) RETURNING id",
+	ctx, cancel := r.store.writeContext(ctx)
+	defer cancel()
+	var createdAt, updatedAt time.Time
+	var lastLoginAt sql.NullTime
+	err = r.store.queryRow(ctx,
+		"INSERT INTO users (email, password, role, display_name, username, locale, timezone, avatar_version) "+
+			"VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id, session_version, version, created_at, updated_at, last_login_at",
 		user.Email,
 		user.Password.Encrypted,
-	).Scan(&user.Id)
+		user.Role,
+		user.DisplayName,
+		user.Username,
+		user.Locale,
+		user.Timezone,
+		user.AvatarVersion,
+	).Scan(&user.Id, &user.SessionVersion, &user.Version, &createdAt, &updatedAt, &lastLoginAt)
 	if err != nil {
-		return err
+		return translateError(ctx, err)
 	}
+	setTimes(user, createdAt, updatedAt, lastLoginAt)
 	return nil
 }
 
-func (r *UserRepository) FindByEmail(email string) (*model.User, error) {
-	user := model.NewEmptyUser()
-	err := r.store.db.QueryRow(
-		"SELECT id, email, password FROM users WHERE email = $1",
-		email,
-	).Scan(&user.Id, &user.Email, &user.Password.Encrypted)
+func (r *UserRepository) FindByEmail(ctx context.Context, email string) (*model.User, error) {
+	ctx, cancel := r.store.readContext(ctx)
+	defer cancel()
+	user, err := scanUser(r.store.readRow(ctx, "SELECT "+userColumns+" FROM users WHERE email = $1", email).Scan)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, store.ErrRecordNotFound
 		}
-		return nil, err
+		return nil, translateError(ctx, err)
 	}
 	return user, nil
 }
 
-func (r *UserRepository) FindById(id int) (*model.User, error) {
-	user := model.NewEmptyUser()
-	err := r.store.db.QueryRow(
-		"SELECT id, email, password FROM users WHERE id = $1",
-		id).Scan(&user.Id, &user.Email, &user.Password.Encrypted)
+func (r *UserRepository) FindById(ctx context.Context, id int) (*model.User, error) {
+	ctx, cancel := r.store.readContext(ctx)
+	defer cancel()
+	user, err := scanUser(r.store.readRow(ctx, "SELECT "+userColumns+" FROM users WHERE id = $1", id).Scan)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, store.ErrRecordNotFound
 		}
-		return nil, err
+		return nil, translateError(ctx, err)
 	}
 	return user, nil
 }
 
-func (r *UserRepository) AllUsers() ([]*model.User, error) {
-	rows, err := r.store.db.Query("SELECT id, email FROM users")
+func (r *UserRepository) AllUsers(ctx context.Context) ([]*model.User, error) {
+	ctx, cancel := r.store.readContext(ctx)
+	defer cancel()
+	rows, err := r.store.readQuery(ctx, "SELECT id, email, role, "+userProfileColumns+" FROM users")
+	if err != nil {
+		return nil, translateError(ctx, err)
+	}
 	defer func(rows *sql.Rows) {
 		err := rows.Close()
 		if err != nil {
@@ -65,38 +112,103 @@ func (r *UserRepository) AllUsers() ([]*model.User, error) {
 		}
 	}(rows)
 
-	if err != nil {
-		return nil, store.ErrRecordNotFound
-	}
-
 	var users []*model.User
 	for rows.Next() {
 		user := &model.User{}
-		err = rows.Scan(&user.Id, &user.Email)
+		var createdAt, updatedAt time.Time
+		var lastLoginAt sql.NullTime
+		err = rows.Scan(&user.Id, &user.Email, &user.Role,
+			&user.DisplayName, &user.Username, &user.Locale, &user.Timezone, &user.AvatarVersion,
+			&createdAt, &updatedAt, &lastLoginAt)
 		if err != nil {
 			return nil, store.ErrDatabaseInternal
 		}
+		setTimes(user, createdAt, updatedAt, lastLoginAt)
 		users = append(users, user)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, translateError(ctx, err)
+	}
 	return users, nil
 }
 
-func (r *UserRepository) Update(user *model.User) error {
+func (r *UserRepository) Update(ctx context.Context, user *model.User) error {
 	err := user.BeforeCreateOrUpdate()
 	if err != nil {
 		return err
 	}
-	_, err = r.store.db.Exec("UPDATE users SET email = $2, password = $3 WHERE id = $1", user.Id, user.Email, user.Password.Encrypted)
+	ctx, cancel := r.store.writeContext(ctx)
+	defer cancel()
+	var createdAt, updatedAt time.Time
+	var lastLoginAt sql.NullTime
+	err = r.store.queryRow(ctx,
+		"UPDATE users SET email = $2, password = $3, role = $4, session_version = $5, "+
+			"display_name = $7, username = $8, locale = $9, timezone = $10, avatar_version = $11, updated_at = now(), "+
+			"version = version + 1 "+
+			"WHERE id = $1 AND version = $6 RETURNING version, created_at, updated_at, last_login_at",
+		user.Id,
+		user.Email,
+		user.Password.Encrypted,
+		user.Role,
+		user.SessionVersion,
+		user.Version,
+		user.DisplayName,
+		user.Username,
+		user.Locale,
+		user.Timezone,
+		user.AvatarVersion,
+	).Scan(&user.Version, &createdAt, &updatedAt, &lastLoginAt)
+	if err == sql.ErrNoRows {
+		return r.missingOrConflict(ctx, user.Id)
+	}
 	if err != nil {
-		return err
+		return translateError(ctx, err)
 	}
+	setTimes(user, createdAt, updatedAt, lastLoginAt)
 	return nil
 }
 
-func (r *UserRepository) Delete(user *model.User) error {
-	_, err := r.store.db.Exec("DELETE FROM users WHERE id = $1", user.Id)
+func (r *UserRepository) RecordLogin(ctx context.Context, user *model.User) error {
+	ctx, cancel := r.store.writeContext(ctx)
+	defer cancel()
+	var lastLoginAt time.Time
+	err := r.store.queryRow(ctx,
+		"UPDATE users SET last_login_at = now() WHERE id = $1 RETURNING last_login_at",
+		user.Id,
+	).Scan(&lastLoginAt)
+	if err == sql.ErrNoRows {
+		return store.ErrRecordNotFound
+	}
 	if err != nil {
-		return err
+		return translateError(ctx, err)
+	}
+	lastLoginAt = lastLoginAt.UTC()
+	user.LastLoginAt = &lastLoginAt
+	return nil
+}
+
+func (r *UserRepository) Delete(ctx context.Context, user *model.User) error {
+	ctx, cancel := r.store.writeContext(ctx)
+	defer cancel()
+	result, err := r.store.exec(ctx, "DELETE FROM users WHERE id = $1 AND version = $2", user.Id, user.Version)
+	if err != nil {
+		return translateError(ctx, err)
+	}
+	if deleted, err := result.RowsAffected(); err != nil || deleted == 0 {
+		return r.missingOrConflict(ctx, user.Id)
 	}
 	return nil
 }
+
+// missingOrConflict tells a deleted user from a newer version.
+func (r *UserRepository) missingOrConflict(ctx context.Context, id int) error {
+	var exist bool
+	err := r.store.queryRow(ctx, "SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)", id).Scan(&exist)
+	if err != nil {
+		return translateError(ctx, err)
+	}
+	if !exist {
+		return store.ErrRecordNotFound
+	}
+	return store.ErrVersionConflict
+}
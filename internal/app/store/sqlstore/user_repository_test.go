@@ -3,6 +3,7 @@ package sqlstore_test
 import (
 	"awesomeProject/internal/app/store"
 	"awesomeProject/internal/app/store/sqlstore"
+	"context"
 	"github.com/stretchr/testify/assert"
 	"testing"
 )
@@ -14,7 +15,7 @@ func TestUserRepository_Create(t *testing.T) {
 	s := sqlstore.NewStore(db)
 	userGen := store.TestUserHelper(t)
 	user := userGen()
-	err := s.UserRepository().Create(user)
+	err := s.UserRepository().Create(context.Background(), user)
 	assert.NoError(t, err)
 	assert.NotNil(t, user)
 }
@@ -27,16 +28,16 @@ func TestUserRepository_FindByEmail(t *testing.T) {
 
 	email := "abc@gmail.com"
 
-	user, err := s.UserRepository().FindByEmail(email)
+	user, err := s.UserRepository().FindByEmail(context.Background(), email)
 	assert.EqualError(t, err, store.ErrRecordNotFound.Error())
 
 	userGen := store.TestUserHelper(t)
 	user = userGen()
-	err = s.UserRepository().Create(user)
+	err = s.UserRepository().Create(context.Background(), user)
 	assert.NoError(t, err)
 	assert.NotNil(t, user)
 
-	user, err = s.UserRepository().FindByEmail(email)
+	user, err = s.UserRepository().FindByEmail(context.Background(), email)
 	assert.NoError(t, err)
 	assert.Equal(t, email, user.Email)
 }
@@ -48,11 +49,11 @@ func TestUserRepository_FindById(t *testing.T) {
 	s := sqlstore.NewStore(db)
 	userGen := store.TestUserHelper(t)
 	user := userGen()
-	err := s.UserRepository().Create(user)
+	err := s.UserRepository().Create(context.Background(), user)
 	assert.NoError(t, err)
 	assert.NotNil(t, user)
 
-	returnedUser, err := s.UserRepository().FindById(user.Id)
+	returnedUser, err := s.UserRepository().FindById(context.Background(), user.Id)
 	assert.NoError(t, err)
 	assert.Equal(t, returnedUser.Id, user.Id)
 }
@@ -64,15 +65,15 @@ func TestUserRepository_AllUsers(t *testing.T) {
 	s := sqlstore.NewStore(db)
 	userGen := store.TestUserHelper(t, 1, "abcabcabc@mail.com", "1234567890")
 	user := userGen()
-	err := s.UserRepository().Create(user)
+	err := s.UserRepository().Create(context.Background(), user)
 	assert.NoError(t, err)
 	assert.NotNil(t, user)
 
 	userGen = store.TestUserHelper(t, 2, "abcabc@mail.com", "1234567890")
 	user = userGen()
-	err = s.UserRepository().Create(user)
+	err = s.UserRepository().Create(context.Background(), user)
 
-	users, err := s.UserRepository().AllUsers()
+	users, err := s.UserRepository().AllUsers(context.Background())
 	assert.NoError(t, err)
 	assert.Equal(t, 2, len(users))
 }
@@ -84,13 +85,13 @@ func TestUserRepository_Update(t *testing.T) {
 	s := sqlstore.NewStore(db)
 	userGen := store.TestUserHelper(t)
 	user := userGen()
-	err := s.UserRepository().Create(user)
+	err := s.UserRepository().Create(context.Background(), user)
 	assert.NoError(t, err)
 	assert.NotNil(t, user)
 
 	newEmail := "abababa@mail.com"
 	user.Email = newEmail
-	err = s.UserRepository().Update(user)
+	err = s.UserRepository().Update(context.Background(), user)
 	assert.NoError(t, err)
 	assert.Equal(t, newEmail, user.Email)
 }
@@ -102,14 +103,35 @@ func TestUserRepository_Delete(t *testing.T) {
 	s := sqlstore.NewStore(db)
 	userGen := store.TestUserHelper(t)
 	user := userGen()
-	err := s.UserRepository().Create(user)
+	err := s.UserRepository().Create(context.Background(), user)
 	assert.NoError(t, err)
 	assert.NotNil(t, user)
 
-	err = s.UserRepository().Delete(user)
+	err = s.UserRepository().Delete(context.Background(), user)
 	assert.NoError(t, err)
 
-	users, err := s.UserRepository().AllUsers()
+	users, err := s.UserRepository().AllUsers(context.Background())
 	assert.NoError(t, err)
 	assert.Equal(t, 0, len(users))
 }
+
+func TestUserRepository_StaleVersion(t *testing.T) {
+	db, teardown := sqlstore.TestDBHelper(t, false)
+	defer teardown("users")
+
+	s := sqlstore.NewStore(db)
+	userGen := store.TestUserHelper(t)
+	user := userGen()
+	err := s.UserRepository().Create(context.Background(), user)
+	assert.NoError(t, err)
+
+	stale := *user
+	user.Email = "abababa@mail.com"
+	err = s.UserRepository().Update(context.Background(), user)
+	assert.NoError(t, err)
+	assert.Equal(t, stale.Version+1, user.Version)
+
+	stale.Email = "bababab@mail.com"
+	assert.ErrorIs(t, s.UserRepository().Update(context.Background(), &stale), store.ErrVersionConflict)
+	assert.ErrorIs(t, s.UserRepository().Delete(context.Background(), &stale), store.ErrVersionConflict)
+}
@@ -1,5 +1,10 @@
 package store
 
+import "context"
+
 type Store interface {
 	UserRepository() UserRepository
+	IdempotencyRepository() IdempotencyRepository
+	// WithTx keeps the changes made through the store given to fn only when fn returns nil.
+	WithTx(ctx context.Context, fn func(Store) error) error
 }
@@ -0,0 +1,521 @@
+// Package storetest is the conformance suite of the store.Store implementations.
+package storetest
+
+import (
+	"awesomeProject/internal/app/model"
+	"awesomeProject/internal/app/store"
+	"context"
+	"errors"
+	"fmt"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// concurrency is the number of goroutines of the concurrent cases.
+const concurrency = 20
+
+// Run runs the conformance suite, newStore is called for every case and must return an empty store.
+func Run(t *testing.T, newStore func(t *testing.T) store.Store) {
+	testCases := []struct {
+		key string
+		run func(t *testing.T, s store.Store)
+	}{
+		{key: "create and find", run: testCreateAndFind},
+		{key: "create with taken email", run: testCreateTakenEmail},
+		{key: "ids are not reused", run: testIdsNotReused},
+		{key: "update", run: testUpdate},
+		{key: "update validates and encrypts", run: testUpdateValidatesAndEncrypts},
+		{key: "update with taken email", run: testUpdateTakenEmail},
+		{key: "profile", run: testProfile},
+		{key: "profile is validated", run: testProfileValidated},
+		{key: "taken username", run: testTakenUsername},
+		{key: "timestamps", run: testTimestamps},
+		{key: "record login", run: testRecordLogin},
+		{key: "stale version", run: testStaleVersion},
+		{key: "missing user", run: testMissingUser},
+		{key: "returned users are copies", run: testReturnedUsersAreCopies},
+		{key: "cancelled context", run: testCancelledContext},
+		{key: "concurrent creates", run: testConcurrentCreates},
+		{key: "concurrent updates", run: testConcurrentUpdates},
+		{key: "transaction commit", run: testTxCommit},
+		{key: "transaction rollback", run: testTxRollback},
+		{key: "idempotency reserve", run: testIdempotencyReserve},
+		{key: "idempotency release", run: testIdempotencyRelease},
+		{key: "idempotency delete expired", run: testIdempotencyDeleteExpired},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.key, func(t *testing.T) {
+			testCase.run(t, newStore(t))
+		})
+	}
+}
+
+func newUser(email string) *model.User {
+	return &model.User{
+		Email:    email,
+		Password: &model.Password{Original: "super1234pass"},
+	}
+}
+
+func create(t *testing.T, s store.Store, email string) *model.User {
+	t.Helper()
+	user := newUser(email)
+	require.NoError(t, s.UserRepository().Create(context.Background(), user))
+	return user
+}
+
+func testCreateAndFind(t *testing.T, s store.Store) {
+	user := create(t, s, "abc@mail.com")
+	assert.NotZero(t, user.Id)
+	assert.Equal(t, 1, user.Version)
+	assert.Equal(t, model.RoleBasic, user.Role)
+
+	byId, err := s.UserRepository().FindById(context.Background(), user.Id)
+	require.NoError(t, err)
+	assert.Equal(t, user.Email, byId.Email)
+	assert.True(t, byId.HasSamePassword("super1234pass"))
+
+	byEmail, err := s.UserRepository().FindByEmail(context.Background(), user.Email)
+	require.NoError(t, err)
+	assert.Equal(t, user.Id, byEmail.Id)
+	assert.Equal(t, user.Version, byEmail.Version)
+}
+
+func testCreateTakenEmail(t *testing.T, s store.Store) {
+	create(t, s, "abc@mail.com")
+	err := s.UserRepository().Create(context.Background(), newUser("abc@mail.com"))
+	assert.ErrorIs(t, err, store.ErrEmailAlreadyExists)
+
+	users, err := s.UserRepository().AllUsers(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, users, 1)
+}
+
+func testIdsNotReused(t *testing.T, s store.Store) {
+	first := create(t, s, "first@mail.com")
+	second := create(t, s, "second@mail.com")
+	require.NoError(t, s.UserRepository().Delete(context.Background(), first))
+	third := create(t, s, "third@mail.com")
+
+	assert.NotEqual(t, first.Id, third.Id)
+	assert.NotEqual(t, second.Id, third.Id)
+	returnedUser, err := s.UserRepository().FindById(context.Background(), second.Id)
+	require.NoError(t, err)
+	assert.Equal(t, "second@mail.com", returnedUser.Email)
+
+	users, err := s.UserRepository().AllUsers(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, users, 2)
+}
+
+func testUpdate(t *testing.T, s store.Store) {
+	user := create(t, s, "abc@mail.com")
+	user.Email = "new@mail.com"
+	user.SessionVersion++
+	require.NoError(t, s.UserRepository().Update(context.Background(), user))
+	assert.Equal(t, 2, user.Version)
+
+	returnedUser, err := s.UserRepository().FindById(context.Background(), user.Id)
+	require.NoError(t, err)
+	assert.Equal(t, "new@mail.com", returnedUser.Email)
+	assert.Equal(t, 1, returnedUser.SessionVersion)
+	assert.Equal(t, 2, returnedUser.Version)
+	_, err = s.UserRepository().FindByEmail(context.Background(), "abc@mail.com")
+	assert.ErrorIs(t, err, store.ErrRecordNotFound)
+}
+
+func testUpdateValidatesAndEncrypts(t *testing.T, s store.Store) {
+	user := create(t, s, "abc@mail.com")
+	user.Password = &model.Password{Original: "another1234pass"}
+	require.NoError(t, s.UserRepository().Update(context.Background(), user))
+	returnedUser, err := s.UserRepository().FindById(context.Background(), user.Id)
+	require.NoError(t, err)
+	assert.True(t, returnedUser.HasSamePassword("another1234pass"))
+
+	returnedUser.Email = "invalid"
+	var validationErrors validation.Errors
+	assert.True(t, errors.As(s.UserRepository().Update(context.Background(), returnedUser), &validationErrors))
+	returnedUser, err = s.UserRepository().FindById(context.Background(), user.Id)
+	require.NoError(t, err)
+	assert.Equal(t, "abc@mail.com", returnedUser.Email)
+}
+
+func testUpdateTakenEmail(t *testing.T, s store.Store) {
+	create(t, s, "first@mail.com")
+	second := create(t, s, "second@mail.com")
+	second.Email = "first@mail.com"
+	assert.ErrorIs(t, s.UserRepository().Update(context.Background(), second), store.ErrEmailAlreadyExists)
+}
+
+func testProfile(t *testing.T, s store.Store) {
+	user := newUser("abc@mail.com")
+	require.NoError(t, s.UserRepository().Create(context.Background(), user))
+	assert.Equal(t, model.DefaultLocale, user.Locale)
+	assert.Equal(t, model.DefaultTimezone, user.Timezone)
+
+	user.DisplayName = "Andrew V."
+	user.Username = "andrvat"
+	user.Locale = "ru-RU"
+	user.Timezone = "Asia/Novosibirsk"
+	user.AvatarVersion = "0123456789abcdef"
+	require.NoError(t, s.UserRepository().Update(context.Background(), user))
+
+	returnedUser, err := s.UserRepository().FindByEmail(context.Background(), user.Email)
+	require.NoError(t, err)
+	assert.Equal(t, "Andrew V.", returnedUser.DisplayName)
+	assert.Equal(t, "andrvat", returnedUser.Username)
+	assert.Equal(t, "ru-RU", returnedUser.Locale)
+	assert.Equal(t, "Asia/Novosibirsk", returnedUser.Timezone)
+	assert.Equal(t, "0123456789abcdef", returnedUser.AvatarVersion)
+
+	users, err := s.UserRepository().AllUsers(context.Background())
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	assert.Equal(t, "andrvat", users[0].Username)
+	assert.True(t, users[0].CreatedAt.Equal(user.CreatedAt))
+}
+
+func testProfileValidated(t *testing.T, s store.Store) {
+	testCases := []struct {
+		key    string
+		change func(user *model.User)
+	}{
+		{key: "long display name", change: func(user *model.User) { user.DisplayName = strings.Repeat("a", 65) }},
+		{key: "short username", change: func(user *model.User) { user.Username = "ab" }},
+		{key: "uppercase username", change: func(user *model.User) { user.Username = "Andrvat" }},
+		{key: "locale", change: func(user *model.User) { user.Locale = "english" }},
+		{key: "timezone", change: func(user *model.User) { user.Timezone = "Mars/Olympus_Mons" }},
+		{key: "local timezone", change: func(user *model.User) { user.Timezone = "Local" }},
+	}
+	user := create(t, s, "abc@mail.com")
+
+	for _, testCase := range testCases {
+		t.Run(testCase.key, func(t *testing.T) {
+			changed := *user
+			testCase.change(&changed)
+			var validationErrors validation.Errors
+			assert.True(t, errors.As(s.UserRepository().Update(context.Background(), &changed), &validationErrors))
+
+			created := newUser("other@mail.com")
+			testCase.change(created)
+			assert.True(t, errors.As(s.UserRepository().Create(context.Background(), created), &validationErrors))
+		})
+	}
+}
+
+func testTakenUsername(t *testing.T, s store.Store) {
+	// Users without a username do not conflict.
+	create(t, s, "other@mail.com")
+	first := newUser("first@mail.com")
+	first.Username = "andrvat"
+	require.NoError(t, s.UserRepository().Create(context.Background(), first))
+
+	second := newUser("second@mail.com")
+	second.Username = "andrvat"
+	assert.ErrorIs(t, s.UserRepository().Create(context.Background(), second), store.ErrUsernameAlreadyExists)
+
+	second.Username = ""
+	require.NoError(t, s.UserRepository().Create(context.Background(), second))
+	second.Username = "andrvat"
+	assert.ErrorIs(t, s.UserRepository().Update(context.Background(), second), store.ErrUsernameAlreadyExists)
+
+	// A user keeps its own username through updates.
+	first.DisplayName = "Andrew"
+	assert.NoError(t, s.UserRepository().Update(context.Background(), first))
+}
+
+func testTimestamps(t *testing.T, s store.Store) {
+	before := time.Now()
+	user := create(t, s, "abc@mail.com")
+	assert.WithinDuration(t, before, user.CreatedAt, time.Minute)
+	assert.True(t, user.UpdatedAt.Equal(user.CreatedAt))
+	assert.Nil(t, user.LastLoginAt)
+	createdAt := user.CreatedAt
+
+	returnedUser, err := s.UserRepository().FindById(context.Background(), user.Id)
+	require.NoError(t, err)
+	assert.True(t, returnedUser.CreatedAt.Equal(createdAt))
+	assert.True(t, returnedUser.UpdatedAt.Equal(user.UpdatedAt))
+	assert.Nil(t, returnedUser.LastLoginAt)
+
+	// Times set by callers are ignored.
+	time.Sleep(time.Millisecond)
+	user.DisplayName = "Andrew"
+	user.CreatedAt = time.Time{}
+	require.NoError(t, s.UserRepository().Update(context.Background(), user))
+	assert.True(t, user.CreatedAt.Equal(createdAt))
+	assert.True(t, user.UpdatedAt.After(createdAt))
+
+	returnedUser, err = s.UserRepository().FindById(context.Background(), user.Id)
+	require.NoError(t, err)
+	assert.True(t, returnedUser.CreatedAt.Equal(createdAt))
+	assert.True(t, returnedUser.UpdatedAt.Equal(user.UpdatedAt))
+}
+
+func testRecordLogin(t *testing.T, s store.Store) {
+	user := create(t, s, "abc@mail.com")
+	updatedAt := user.UpdatedAt
+	before := time.Now()
+
+	require.NoError(t, s.UserRepository().RecordLogin(context.Background(), user))
+	require.NotNil(t, user.LastLoginAt)
+	assert.WithinDuration(t, before, *user.LastLoginAt, time.Minute)
+	assert.Equal(t, 1, user.Version)
+
+	returnedUser, err := s.UserRepository().FindById(context.Background(), user.Id)
+	require.NoError(t, err)
+	require.NotNil(t, returnedUser.LastLoginAt)
+	assert.True(t, returnedUser.LastLoginAt.Equal(*user.LastLoginAt))
+	assert.True(t, returnedUser.UpdatedAt.Equal(updatedAt))
+	assert.Equal(t, 1, returnedUser.Version)
+
+	// An update keeps the last login.
+	returnedUser.DisplayName = "Andrew"
+	require.NoError(t, s.UserRepository().Update(context.Background(), returnedUser))
+	require.NotNil(t, returnedUser.LastLoginAt)
+	assert.True(t, returnedUser.LastLoginAt.Equal(*user.LastLoginAt))
+
+	require.NoError(t, s.UserRepository().Delete(context.Background(), returnedUser))
+	assert.ErrorIs(t, s.UserRepository().RecordLogin(context.Background(), user), store.ErrRecordNotFound)
+}
+
+func testStaleVersion(t *testing.T, s store.Store) {
+	user := create(t, s, "abc@mail.com")
+	stale, err := s.UserRepository().FindById(context.Background(), user.Id)
+	require.NoError(t, err)
+
+	user.Email = "new@mail.com"
+	require.NoError(t, s.UserRepository().Update(context.Background(), user))
+
+	stale.Email = "stale@mail.com"
+	assert.ErrorIs(t, s.UserRepository().Update(context.Background(), stale), store.ErrVersionConflict)
+	assert.ErrorIs(t, s.UserRepository().Delete(context.Background(), stale), store.ErrVersionConflict)
+	_, err = s.UserRepository().FindById(context.Background(), user.Id)
+	assert.NoError(t, err)
+}
+
+func testMissingUser(t *testing.T, s store.Store) {
+	user := create(t, s, "abc@mail.com")
+	require.NoError(t, s.UserRepository().Delete(context.Background(), user))
+
+	_, err := s.UserRepository().FindById(context.Background(), user.Id)
+	assert.ErrorIs(t, err, store.ErrRecordNotFound)
+	_, err = s.UserRepository().FindByEmail(context.Background(), user.Email)
+	assert.ErrorIs(t, err, store.ErrRecordNotFound)
+	assert.ErrorIs(t, s.UserRepository().Update(context.Background(), user), store.ErrRecordNotFound)
+	assert.ErrorIs(t, s.UserRepository().Delete(context.Background(), user), store.ErrRecordNotFound)
+}
+
+func testReturnedUsersAreCopies(t *testing.T, s store.Store) {
+	user := create(t, s, "abc@mail.com")
+	user.Email = "changed@mail.com"
+
+	returnedUser, err := s.UserRepository().FindById(context.Background(), user.Id)
+	require.NoError(t, err)
+	assert.Equal(t, "abc@mail.com", returnedUser.Email)
+	returnedUser.Email = "changed@mail.com"
+
+	returnedUser, err = s.UserRepository().FindByEmail(context.Background(), "abc@mail.com")
+	require.NoError(t, err)
+	assert.Equal(t, "abc@mail.com", returnedUser.Email)
+}
+
+func testCancelledContext(t *testing.T, s store.Store) {
+	user := create(t, s, "abc@mail.com")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := s.UserRepository().FindById(ctx, user.Id)
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = s.UserRepository().AllUsers(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.ErrorIs(t, s.UserRepository().Create(ctx, newUser("other@mail.com")), context.Canceled)
+	assert.ErrorIs(t, s.UserRepository().Delete(ctx, user), context.Canceled)
+}
+
+func testConcurrentCreates(t *testing.T, s store.Store) {
+	var wg sync.WaitGroup
+	errs := make([]error, concurrency)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			email := fmt.Sprintf("user%d@mail.com", i%(concurrency/2))
+			errs[i] = s.UserRepository().Create(context.Background(), newUser(email))
+		}(i)
+	}
+	wg.Wait()
+
+	taken := 0
+	for _, err := range errs {
+		if err != nil {
+			assert.ErrorIs(t, err, store.ErrEmailAlreadyExists)
+			taken++
+		}
+	}
+	assert.Equal(t, concurrency/2, taken)
+
+	users, err := s.UserRepository().AllUsers(context.Background())
+	require.NoError(t, err)
+	ids := make(map[int]bool)
+	for _, user := range users {
+		ids[user.Id] = true
+	}
+	assert.Len(t, ids, concurrency/2)
+}
+
+func testConcurrentUpdates(t *testing.T, s store.Store) {
+	user := create(t, s, "abc@mail.com")
+
+	var wg sync.WaitGroup
+	errs := make([]error, concurrency)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			copied := *user
+			copied.Password = &model.Password{Encrypted: user.Password.Encrypted}
+			copied.Email = fmt.Sprintf("user%d@mail.com", i)
+			errs[i] = s.UserRepository().Update(context.Background(), &copied)
+		}(i)
+	}
+	wg.Wait()
+
+	updated := 0
+	for _, err := range errs {
+		if err == nil {
+			updated++
+			continue
+		}
+		assert.ErrorIs(t, err, store.ErrVersionConflict)
+	}
+	assert.Equal(t, 1, updated)
+}
+
+func testTxCommit(t *testing.T, s store.Store) {
+	var user *model.User
+	err := s.WithTx(context.Background(), func(tx store.Store) error {
+		user = create(t, tx, "abc@mail.com")
+		return tx.WithTx(context.Background(), func(nested store.Store) error {
+			user.Email = "new@mail.com"
+			return nested.UserRepository().Update(context.Background(), user)
+		})
+	})
+	require.NoError(t, err)
+
+	returnedUser, err := s.UserRepository().FindById(context.Background(), user.Id)
+	require.NoError(t, err)
+	assert.Equal(t, "new@mail.com", returnedUser.Email)
+}
+
+func testTxRollback(t *testing.T, s store.Store) {
+	user := create(t, s, "abc@mail.com")
+	errFailed := errors.New("failed")
+
+	err := s.WithTx(context.Background(), func(tx store.Store) error {
+		create(t, tx, "other@mail.com")
+		user.Email = "new@mail.com"
+		if err := tx.UserRepository().Update(context.Background(), user); err != nil {
+			return err
+		}
+		return errFailed
+	})
+	assert.ErrorIs(t, err, errFailed)
+
+	assert.Panics(t, func() {
+		_ = s.WithTx(context.Background(), func(tx store.Store) error {
+			create(t, tx, "panic@mail.com")
+			panic("failed")
+		})
+	})
+
+	users, err := s.UserRepository().AllUsers(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, users, 1)
+	returnedUser, err := s.UserRepository().FindById(context.Background(), user.Id)
+	require.NoError(t, err)
+	assert.Equal(t, "abc@mail.com", returnedUser.Email)
+}
+
+func newIdempotencyRecord(key string, now time.Time) *model.IdempotencyRecord {
+	return &model.IdempotencyRecord{
+		Scope:       "POST /v1/users",
+		Key:         key,
+		RequestHash: "hash",
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(time.Hour),
+	}
+}
+
+func testIdempotencyReserve(t *testing.T, s store.Store) {
+	now := time.Now().Truncate(time.Microsecond)
+	record := newIdempotencyRecord("key", now)
+	existing, err := s.IdempotencyRepository().Reserve(context.Background(), record)
+	require.NoError(t, err)
+	assert.Nil(t, existing)
+
+	existing, err = s.IdempotencyRepository().Reserve(context.Background(), record)
+	require.NoError(t, err)
+	if assert.NotNil(t, existing) {
+		assert.False(t, existing.IsCompleted())
+	}
+
+	record.Status = http.StatusCreated
+	record.Headers = map[string]string{"Location": "/v1/users/1"}
+	record.Body = []byte("{}")
+	record.ExpiresAt = now.Add(2 * time.Hour)
+	require.NoError(t, s.IdempotencyRepository().Complete(context.Background(), record))
+
+	// Completing extends the reservation, the record outlives its original expiry.
+	deleted, err := s.IdempotencyRepository().DeleteExpired(context.Background(), now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), deleted)
+
+	existing, err = s.IdempotencyRepository().Reserve(context.Background(), record)
+	require.NoError(t, err)
+	if assert.NotNil(t, existing) {
+		assert.Equal(t, http.StatusCreated, existing.Status)
+		assert.Equal(t, record.Headers, existing.Headers)
+		assert.Equal(t, record.Body, existing.Body)
+		assert.Equal(t, "hash", existing.RequestHash)
+	}
+
+	retry := *record
+	retry.CreatedAt = now.Add(3 * time.Hour)
+	retry.ExpiresAt = now.Add(4 * time.Hour)
+	existing, err = s.IdempotencyRepository().Reserve(context.Background(), &retry)
+	require.NoError(t, err)
+	assert.Nil(t, existing)
+}
+
+func testIdempotencyRelease(t *testing.T, s store.Store) {
+	record := newIdempotencyRecord("key", time.Now())
+	_, err := s.IdempotencyRepository().Reserve(context.Background(), record)
+	require.NoError(t, err)
+	require.NoError(t, s.IdempotencyRepository().Release(context.Background(), record))
+
+	existing, err := s.IdempotencyRepository().Reserve(context.Background(), record)
+	require.NoError(t, err)
+	assert.Nil(t, existing)
+}
+
+func testIdempotencyDeleteExpired(t *testing.T, s store.Store) {
+	now := time.Now()
+	expired := newIdempotencyRecord("expired", now)
+	expired.ExpiresAt = now.Add(-time.Hour)
+	for _, record := range []*model.IdempotencyRecord{expired, newIdempotencyRecord("live", now)} {
+		_, err := s.IdempotencyRepository().Reserve(context.Background(), record)
+		require.NoError(t, err)
+	}
+
+	deleted, err := s.IdempotencyRepository().DeleteExpired(context.Background(), now)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+}
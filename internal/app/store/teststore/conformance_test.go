@@ -0,0 +1,14 @@
+package teststore_test
+
+import (
+	"awesomeProject/internal/app/store"
+	"awesomeProject/internal/app/store/storetest"
+	"awesomeProject/internal/app/store/teststore"
+	"testing"
+)
+
+func TestStore_conformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Store {
+		return teststore.NewStore()
+	})
+}
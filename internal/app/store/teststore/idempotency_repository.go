@@ -0,0 +1,79 @@
+package teststore
+
+import (
+	"awesomeProject/internal/app/model"
+	"context"
+	"sync"
+	"time"
+)
+
+type IdempotencyRepository struct {
+	store *Store
+
+	mutex   sync.Mutex
+	records map[[2]string]*model.IdempotencyRecord
+}
+
+func (r *IdempotencyRepository) Reserve(ctx context.Context, record *model.IdempotencyRecord) (*model.IdempotencyRecord, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	id := [2]string{record.Scope, record.Key}
+	if existing, exist := r.records[id]; exist && existing.ExpiresAt.After(record.CreatedAt) {
+		copied := *existing
+		return &copied, nil
+	}
+	copied := *record
+	r.records[id] = &copied
+	return nil, nil
+}
+
+func (r *IdempotencyRepository) Complete(ctx context.Context, record *model.IdempotencyRecord) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if existing, exist := r.records[[2]string{record.Scope, record.Key}]; exist {
+		existing.Status = record.Status
+		existing.Headers = record.Headers
+		existing.Body = record.Body
+		existing.ExpiresAt = record.ExpiresAt
+	}
+	return nil
+}
+
+func (r *IdempotencyRepository) Release(ctx context.Context, record *model.IdempotencyRecord) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	id := [2]string{record.Scope, record.Key}
+	if existing, exist := r.records[id]; exist && !existing.IsCompleted() {
+		delete(r.records, id)
+	}
+	return nil
+}
+
+func (r *IdempotencyRepository) DeleteExpired(ctx context.Context, at time.Time) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var deleted int64
+	for id, record := range r.records {
+		if !record.ExpiresAt.After(at) {
+			delete(r.records, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,103 @@
+package teststore
+
+import (
+	"awesomeProject/internal/app/model"
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// snapshot is the file format of Save and Load, idempotency records are not kept.
+type snapshot struct {
+	LastId int            `json:"last_id"`
+	Users  []snapshotUser `json:"users"`
+}
+
+type snapshotUser struct {
+	Id                int    `json:"id"`
+	Email             string `json:"email"`
+	EncryptedPassword string `json:"encrypted_password"`
+	Role              string `json:"role"`
+	SessionVersion    int    `json:"session_version"`
+	Version           int    `json:"version"`
+
+	DisplayName   string     `json:"display_name"`
+	Username      string     `json:"username"`
+	Locale        string     `json:"locale"`
+	Timezone      string     `json:"timezone"`
+	AvatarVersion string     `json:"avatar_version"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	LastLoginAt   *time.Time `json:"last_login_at"`
+}
+
+// Save writes the users of the store to w as JSON.
+func (s *Store) Save(w io.Writer) error {
+	r := s.userRepository
+	r.mutex.RLock()
+	data := snapshot{
+		LastId: r.lastId,
+		Users:  make([]snapshotUser, 0, len(r.usersById)),
+	}
+	for _, user := range r.usersById {
+		data.Users = append(data.Users, snapshotUser{
+			Id:                user.Id,
+			Email:             user.Email,
+			EncryptedPassword: user.Password.Encrypted,
+			Role:              user.Role,
+			SessionVersion:    user.SessionVersion,
+			Version:           user.Version,
+			DisplayName:       user.DisplayName,
+			Username:          user.Username,
+			Locale:            user.Locale,
+			Timezone:          user.Timezone,
+			AvatarVersion:     user.AvatarVersion,
+			CreatedAt:         user.CreatedAt,
+			UpdatedAt:         user.UpdatedAt,
+			LastLoginAt:       user.LastLoginAt,
+		})
+	}
+	r.mutex.RUnlock()
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(data)
+}
+
+// Load replaces the users of the store with the ones written by Save.
+func (s *Store) Load(reader io.Reader) error {
+	var data snapshot
+	if err := json.NewDecoder(reader).Decode(&data); err != nil {
+		return err
+	}
+	usersById := make(map[int]*model.User, len(data.Users))
+	lastId := data.LastId
+	for _, user := range data.Users {
+		usersById[user.Id] = &model.User{
+			Id:             user.Id,
+			Email:          user.Email,
+			Password:       &model.Password{Encrypted: user.EncryptedPassword},
+			Role:           user.Role,
+			SessionVersion: user.SessionVersion,
+			Version:        user.Version,
+			DisplayName:    user.DisplayName,
+			Username:       user.Username,
+			Locale:         user.Locale,
+			Timezone:       user.Timezone,
+			AvatarVersion:  user.AvatarVersion,
+			CreatedAt:      user.CreatedAt,
+			UpdatedAt:      user.UpdatedAt,
+			LastLoginAt:    user.LastLoginAt,
+		}
+		if user.Id > lastId {
+			lastId = user.Id
+		}
+	}
+
+	r := s.userRepository
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.usersById = usersById
+	r.lastId = lastId
+	return nil
+}
@@ -0,0 +1,39 @@
+package teststore_test
+
+import (
+	"awesomeProject/internal/app/store"
+	"awesomeProject/internal/app/store/teststore"
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestStore_SaveLoad(t *testing.T) {
+	s := teststore.NewStore()
+	userGen := store.TestUserHelper(t)
+	kept := userGen()
+	require.NoError(t, s.UserRepository().Create(context.Background(), kept))
+	deleted := userGen()
+	deleted.Email = "deleted@gmail.com"
+	require.NoError(t, s.UserRepository().Create(context.Background(), deleted))
+	require.NoError(t, s.UserRepository().Delete(context.Background(), deleted))
+
+	var buffer bytes.Buffer
+	require.NoError(t, s.Save(&buffer))
+	loaded := teststore.NewStore()
+	require.NoError(t, loaded.Load(&buffer))
+
+	user, err := loaded.UserRepository().FindById(context.Background(), kept.Id)
+	require.NoError(t, err)
+	assert.Equal(t, kept.Email, user.Email)
+	assert.Equal(t, kept.Role, user.Role)
+	assert.Equal(t, kept.Version, user.Version)
+	assert.True(t, user.HasSamePassword("super1234pass"))
+
+	created := userGen()
+	created.Email = "created@gmail.com"
+	require.NoError(t, loaded.UserRepository().Create(context.Background(), created))
+	assert.Greater(t, created.Id, deleted.Id)
+}
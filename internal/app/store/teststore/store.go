@@ -3,22 +3,37 @@ package teststore
 import (
 	"awesomeProject/internal/app/model"
 	"awesomeProject/internal/app/store"
+	"sync"
 )
 
 type Store struct {
-	userRepository *UserRepository
+	userRepository        *UserRepository
+	idempotencyRepository *IdempotencyRepository
+
+	// txMutex runs the transactions of a store and of the stores handed out by WithTx one at a time.
+	txMutex *sync.Mutex
+	inTx    bool
 }
 
 func NewStore() *Store {
-	return &Store{}
+	s := &Store{
+		txMutex: &sync.Mutex{},
+	}
+	s.userRepository = &UserRepository{
+		store:     s,
+		usersById: make(map[int]*model.User),
+	}
+	s.idempotencyRepository = &IdempotencyRepository{
+		store:   s,
+		records: make(map[[2]string]*model.IdempotencyRecord),
+	}
+	return s
 }
 
 func (s *Store) UserRepository() store.UserRepository {
-	if s.userRepository == nil {
-		s.userRepository = &UserRepository{
-			store:     s,
-			usersById: make(map[int]*model.User),
-		}
-	}
 	return s.userRepository
 }
+
+func (s *Store) IdempotencyRepository() store.IdempotencyRepository {
+	return s.idempotencyRepository
+}
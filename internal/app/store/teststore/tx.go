@@ -0,0 +1,82 @@
+package teststore
+
+import (
+	"awesomeProject/internal/app/model"
+	"awesomeProject/internal/app/store"
+	"context"
+)
+
+// WithTx restores the repositories when fn fails, transactions are serialized but not isolated.
+func (s *Store) WithTx(ctx context.Context, fn func(store.Store) error) error {
+	if s.inTx {
+		return fn(s)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	users := s.userRepository
+	records := s.idempotencyRepository
+
+	s.txMutex.Lock()
+	defer s.txMutex.Unlock()
+
+	usersById := users.snapshot()
+	recordsById := records.snapshot()
+	committed := false
+	defer func() {
+		if !committed {
+			users.restore(usersById)
+			records.restore(recordsById)
+		}
+	}()
+
+	txStore := &Store{
+		userRepository:        users,
+		idempotencyRepository: records,
+		txMutex:               s.txMutex,
+		inTx:                  true,
+	}
+	if err := fn(txStore); err != nil {
+		return err
+	}
+	committed = true
+	return nil
+}
+
+func (r *UserRepository) snapshot() map[int]*model.User {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	usersById := make(map[int]*model.User, len(r.usersById))
+	for id, user := range r.usersById {
+		usersById[id] = copyUser(user)
+	}
+	return usersById
+}
+
+// restore brings back the users of a snapshot. Ids taken meanwhile are not reused.
+func (r *UserRepository) restore(usersById map[int]*model.User) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.usersById = usersById
+}
+
+func (r *IdempotencyRepository) snapshot() map[[2]string]*model.IdempotencyRecord {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	records := make(map[[2]string]*model.IdempotencyRecord, len(r.records))
+	for id, record := range r.records {
+		copied := *record
+		records[id] = &copied
+	}
+	return records
+}
+
+func (r *IdempotencyRepository) restore(records map[[2]string]*model.IdempotencyRecord) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.records = records
+}
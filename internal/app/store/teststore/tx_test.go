@@ -0,0 +1,57 @@
+package teststore_test
+
+import (
+	"awesomeProject/internal/app/store"
+	"awesomeProject/internal/app/store/teststore"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestStore_WithTx(t *testing.T) {
+	s := teststore.NewStore()
+	userGen := store.TestUserHelper(t)
+	user := userGen()
+
+	err := s.WithTx(context.Background(), func(tx store.Store) error {
+		if err := tx.UserRepository().Create(context.Background(), user); err != nil {
+			return err
+		}
+		return tx.WithTx(context.Background(), func(nested store.Store) error {
+			_, err := nested.UserRepository().FindById(context.Background(), user.Id)
+			return err
+		})
+	})
+	assert.NoError(t, err)
+
+	_, err = s.UserRepository().FindById(context.Background(), user.Id)
+	assert.NoError(t, err)
+}
+
+func TestStore_WithTx_rollback(t *testing.T) {
+	errFailed := errors.New("failed")
+	s := teststore.NewStore()
+	userGen := store.TestUserHelper(t)
+
+	err := s.WithTx(context.Background(), func(tx store.Store) error {
+		if err := tx.UserRepository().Create(context.Background(), userGen()); err != nil {
+			return err
+		}
+		return errFailed
+	})
+	assert.ErrorIs(t, err, errFailed)
+
+	assert.Panics(t, func() {
+		_ = s.WithTx(context.Background(), func(tx store.Store) error {
+			if err := tx.UserRepository().Create(context.Background(), userGen()); err != nil {
+				return err
+			}
+			panic("failed")
+		})
+	})
+
+	users, err := s.UserRepository().AllUsers(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, users)
+}
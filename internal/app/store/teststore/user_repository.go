@@ -3,63 +3,204 @@ package teststore
 import (
 	"awesomeProject/internal/app/model"
 	"awesomeProject/internal/app/store"
-	"errors"
+	"context"
+	"sync"
+	"time"
 )
 
+// UserRepository stores copies, like the sql stores.
 type UserRepository struct {
-	store     *Store
+	store *Store
+
+	mutex     sync.RWMutex
 	usersById map[int]*model.User
+	// lastId is never decreased, deleted ids are not reused.
+	lastId int
 }
 
-func (r *UserRepository) Create(user *model.User) error {
+func (r *UserRepository) Create(ctx context.Context, user *model.User) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	err := user.BeforeCreateOrUpdate()
 	if err != nil {
 		return err
 	}
 
-	user.Id = len(r.usersById) + 1
-	r.usersById[user.Id] = user
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.emailTaken(user.Email, 0) {
+		return store.ErrEmailAlreadyExists
+	}
+	if r.usernameTaken(user.Username, 0) {
+		return store.ErrUsernameAlreadyExists
+	}
+	r.lastId++
+	user.Id = r.lastId
+	user.Version = 1
+	user.CreatedAt = time.Now().UTC()
+	user.UpdatedAt = user.CreatedAt
+	user.LastLoginAt = nil
+	r.usersById[user.Id] = storedUser(user)
 	return nil
 }
 
-func (r *UserRepository) FindByEmail(email string) (*model.User, error) {
+func (r *UserRepository) FindByEmail(ctx context.Context, email string) (*model.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
 	for _, user := range r.usersById {
 		if user.Email == email {
-			return user, nil
+			return copyUser(user), nil
 		}
 	}
 	return nil, store.ErrRecordNotFound
 }
 
-func (r *UserRepository) FindById(id int) (*model.User, error) {
+func (r *UserRepository) FindById(ctx context.Context, id int) (*model.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
 	user, exist := r.usersById[id]
-	if exist {
-		return user, nil
-	} else {
+	if !exist {
 		return nil, store.ErrRecordNotFound
 	}
+	return copyUser(user), nil
 }
 
-func (r *UserRepository) AllUsers() ([]*model.User, error) {
-	v := make([]*model.User, 0, len(r.usersById))
+func (r *UserRepository) AllUsers(ctx context.Context) ([]*model.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
 
+	v := make([]*model.User, 0, len(r.usersById))
 	for _, value := range r.usersById {
-		v = append(v, value)
+		v = append(v, copyUser(value))
 	}
 	return v, nil
 }
 
-func (r *UserRepository) Update(user *model.User) error {
-	_, exist := r.usersById[user.Id]
-	if exist {
-		r.usersById[user.Id] = user
-		return nil
-	} else {
-		return errors.New("cannot update non-existing user")
+func (r *UserRepository) Update(ctx context.Context, user *model.User) error {
+	if err := ctx.Err(); err != nil {
+		return err
 	}
+	err := user.BeforeCreateOrUpdate()
+	if err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	stored, exist := r.usersById[user.Id]
+	if !exist {
+		return store.ErrRecordNotFound
+	}
+	if stored.Version != user.Version {
+		return store.ErrVersionConflict
+	}
+	if r.emailTaken(user.Email, user.Id) {
+		return store.ErrEmailAlreadyExists
+	}
+	if r.usernameTaken(user.Username, user.Id) {
+		return store.ErrUsernameAlreadyExists
+	}
+	user.Version++
+	user.CreatedAt = stored.CreatedAt
+	user.UpdatedAt = time.Now().UTC()
+	user.LastLoginAt = copyTime(stored.LastLoginAt)
+	r.usersById[user.Id] = storedUser(user)
+	return nil
 }
 
-func (r *UserRepository) Delete(user *model.User) error {
+func (r *UserRepository) RecordLogin(ctx context.Context, user *model.User) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	stored, exist := r.usersById[user.Id]
+	if !exist {
+		return store.ErrRecordNotFound
+	}
+	now := time.Now().UTC()
+	stored.LastLoginAt = &now
+	user.LastLoginAt = copyTime(stored.LastLoginAt)
+	return nil
+}
+
+func (r *UserRepository) Delete(ctx context.Context, user *model.User) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	stored, exist := r.usersById[user.Id]
+	if !exist {
+		return store.ErrRecordNotFound
+	}
+	if stored.Version != user.Version {
+		return store.ErrVersionConflict
+	}
 	delete(r.usersById, user.Id)
 	return nil
 }
+
+// emailTaken reports whether a user other than the one with exceptId has email.
+func (r *UserRepository) emailTaken(email string, exceptId int) bool {
+	for id, user := range r.usersById {
+		if id != exceptId && user.Email == email {
+			return true
+		}
+	}
+	return false
+}
+
+// usernameTaken reports whether a user other than the one with exceptId has username. Empty usernames are never taken.
+func (r *UserRepository) usernameTaken(username string, exceptId int) bool {
+	if username == "" {
+		return false
+	}
+	for id, user := range r.usersById {
+		if id != exceptId && user.Username == username {
+			return true
+		}
+	}
+	return false
+}
+
+// storedUser is the copy kept by the repository, without the original password the sql stores do not keep either.
+func storedUser(user *model.User) *model.User {
+	stored := copyUser(user)
+	if stored.Password != nil {
+		stored.Password.Original = ""
+	}
+	return stored
+}
+
+func copyUser(user *model.User) *model.User {
+	copied := *user
+	if user.Password != nil {
+		password := *user.Password
+		copied.Password = &password
+	}
+	copied.LastLoginAt = copyTime(user.LastLoginAt)
+	return &copied
+}
+
+func copyTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	copied := *t
+	return &copied
+}
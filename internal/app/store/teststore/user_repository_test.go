@@ -3,6 +3,7 @@ package teststore_test
 import (
 	"awesomeProject/internal/app/store"
 	"awesomeProject/internal/app/store/teststore"
+	"context"
 	"github.com/stretchr/testify/assert"
 	"testing"
 )
@@ -11,7 +12,7 @@ func TestUserRepository_Create(t *testing.T) {
 	s := teststore.NewStore()
 	userGen := store.TestUserHelper(t)
 	user := userGen()
-	err := s.UserRepository().Create(user)
+	err := s.UserRepository().Create(context.Background(), user)
 	assert.NoError(t, err)
 	assert.NotNil(t, user)
 }
@@ -21,16 +22,16 @@ func TestUserRepository_FindByEmail(t *testing.T) {
 
 	email := "abc@gmail.com"
 
-	user, err := s.UserRepository().FindByEmail(email)
+	user, err := s.UserRepository().FindByEmail(context.Background(), email)
 	assert.EqualError(t, err, store.ErrRecordNotFound.Error())
 
 	userGen := store.TestUserHelper(t)
 	user = userGen()
-	err = s.UserRepository().Create(user)
+	err = s.UserRepository().Create(context.Background(), user)
 	assert.NoError(t, err)
 	assert.NotNil(t, user)
 
-	user, err = s.UserRepository().FindByEmail(email)
+	user, err = s.UserRepository().FindByEmail(context.Background(), email)
 	assert.NoError(t, err)
 	assert.Equal(t, email, user.Email)
 }
@@ -40,11 +41,11 @@ func TestUserRepository_FindById(t *testing.T) {
 
 	userGen := store.TestUserHelper(t)
 	user := userGen()
-	err := s.UserRepository().Create(user)
+	err := s.UserRepository().Create(context.Background(), user)
 	assert.NoError(t, err)
 	assert.NotNil(t, user)
 
-	returnedUser, err := s.UserRepository().FindById(user.Id)
+	returnedUser, err := s.UserRepository().FindById(context.Background(), user.Id)
 	assert.NoError(t, err)
 	assert.Equal(t, returnedUser.Id, user.Id)
 }
@@ -54,15 +55,15 @@ func TestUserRepository_AllUsers(t *testing.T) {
 
 	userGen := store.TestUserHelper(t, 1, "abcabcabc@mail.com", "1234567890")
 	user := userGen()
-	err := s.UserRepository().Create(user)
+	err := s.UserRepository().Create(context.Background(), user)
 	assert.NoError(t, err)
 	assert.NotNil(t, user)
 
 	userGen = store.TestUserHelper(t, 2, "abcabc@mail.com", "1234567890")
 	user = userGen()
-	err = s.UserRepository().Create(user)
+	err = s.UserRepository().Create(context.Background(), user)
 
-	users, err := s.UserRepository().AllUsers()
+	users, err := s.UserRepository().AllUsers(context.Background())
 	assert.NoError(t, err)
 	assert.Equal(t, 2, len(users))
 }
@@ -72,13 +73,13 @@ func TestUserRepository_Update(t *testing.T) {
 
 	userGen := store.TestUserHelper(t)
 	user := userGen()
-	err := s.UserRepository().Create(user)
+	err := s.UserRepository().Create(context.Background(), user)
 	assert.NoError(t, err)
 	assert.NotNil(t, user)
 
 	newEmail := "abababa@mail.com"
 	user.Email = newEmail
-	err = s.UserRepository().Update(user)
+	err = s.UserRepository().Update(context.Background(), user)
 	assert.NoError(t, err)
 	assert.Equal(t, newEmail, user.Email)
 }
@@ -88,14 +89,33 @@ func TestUserRepository_Delete(t *testing.T) {
 
 	userGen := store.TestUserHelper(t)
 	user := userGen()
-	err := s.UserRepository().Create(user)
+	err := s.UserRepository().Create(context.Background(), user)
 	assert.NoError(t, err)
 	assert.NotNil(t, user)
 
-	err = s.UserRepository().Delete(user)
+	err = s.UserRepository().Delete(context.Background(), user)
 	assert.NoError(t, err)
 
-	users, err := s.UserRepository().AllUsers()
+	users, err := s.UserRepository().AllUsers(context.Background())
 	assert.NoError(t, err)
 	assert.Equal(t, 0, len(users))
 }
+
+func TestUserRepository_StaleVersion(t *testing.T) {
+	s := teststore.NewStore()
+
+	userGen := store.TestUserHelper(t)
+	user := userGen()
+	err := s.UserRepository().Create(context.Background(), user)
+	assert.NoError(t, err)
+
+	stale := *user
+	user.Email = "abababa@mail.com"
+	err = s.UserRepository().Update(context.Background(), user)
+	assert.NoError(t, err)
+	assert.Equal(t, stale.Version+1, user.Version)
+
+	stale.Email = "bababab@mail.com"
+	assert.ErrorIs(t, s.UserRepository().Update(context.Background(), &stale), store.ErrVersionConflict)
+	assert.ErrorIs(t, s.UserRepository().Delete(context.Background(), &stale), store.ErrVersionConflict)
+}
@@ -1,12 +1,17 @@
 package store
 
-import "awesomeProject/internal/app/model"
+import (
+	"awesomeProject/internal/app/model"
+	"context"
+)
 
 type UserRepository interface {
-	Create(*model.User) error
-	Update(user *model.User) error
-	Delete(user *model.User) error
-	AllUsers() ([]*model.User, error)
-	FindById(int) (*model.User, error)
-	FindByEmail(string) (*model.User, error)
+	Create(ctx context.Context, user *model.User) error
+	Update(ctx context.Context, user *model.User) error
+	Delete(ctx context.Context, user *model.User) error
+	// RecordLogin sets the last login time of the user to now. It changes neither the version nor the update time.
+	RecordLogin(ctx context.Context, user *model.User) error
+	AllUsers(ctx context.Context) ([]*model.User, error)
+	FindById(ctx context.Context, id int) (*model.User, error)
+	FindByEmail(ctx context.Context, email string) (*model.User, error)
 }
@@ -3,11 +3,22 @@ package main
 import (
 	"awesomeProject/internal/app/apiserver"
 	"flag"
+	"fmt"
 	"log"
+	"os"
 )
 
 var (
 	serverConfigPath string
+	configProfile    string
+	outputFormat     string
+	storeMode        string
+	memoryOptions    apiserver.MemoryOptions
+)
+
+const (
+	storeSql    = "sql"
+	storeMemory = "memory"
 )
 
 func init() {
@@ -15,8 +26,49 @@ func init() {
 		"config-path",
 		"configs/apiserver.toml",
 		"Initialize path to config TOML file")
+	flag.StringVar(&configProfile,
+		"profile",
+		"",
+		"Config profile overlay: dev, test or prod (default $APISERVER_PROFILE or prod)")
+	flag.StringVar(&outputFormat,
+		"output",
+		outputTable,
+		"Output format of administrative commands: table or json")
+	flag.StringVar(&storeMode,
+		"store",
+		storeSql,
+		"Store of the server: sql, or memory to serve without a database")
+	flag.StringVar(&memoryOptions.SeedPath,
+		"seed",
+		"",
+		"JSON or YAML file of users created on start with -store=memory")
+	flag.StringVar(&memoryOptions.SnapshotPath,
+		"snapshot",
+		"",
+		"File the -store=memory state is loaded from on start and saved to on shutdown")
+	flag.StringVar(&memoryOptions.FaultsPath,
+		"faults",
+		"",
+		"JSON or YAML file of latency and errors injected per route with -store=memory")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [command]\n\n", os.Args[0])
+		fmt.Fprint(flag.CommandLine.Output(), commandsUsage)
+		flag.PrintDefaults()
+	}
 }
 
+const commandsUsage = `Commands:
+  serve                                          Start the API server (default)
+  migrate up | down [N] | status | goto N        Manage the database schema
+  user create -email E [-password P] [-role R]   Create a user
+  user list                                      List users
+  user set-role -id N | -email E -role R         Change the role of a user
+  user reset-password -id N | -email E           Set a new password and revoke sessions
+  user delete -id N | -email E                   Delete a user
+  session revoke -id N | -email E                Revoke all sessions of a user
+  config print                                   Print the effective config with secrets redacted
+`
+
 // @title CRUD Basic API Server
 // @version 1.0
 // @description API Server provides CRUD operations
@@ -24,11 +76,46 @@ func init() {
 // @BasePath /
 func main() {
 	flag.Parse()
-	config, err := apiserver.NewConfigFromToml(serverConfigPath)
+	if outputFormat != outputTable && outputFormat != outputJson {
+		log.Fatal(errUnknownOutputFormat)
+	}
+	if storeMode != storeSql && storeMode != storeMemory {
+		log.Fatal(errUnknownStore)
+	}
+	var loadOptions []apiserver.LoadOption
+	if storeMode == storeMemory {
+		loadOptions = append(loadOptions, apiserver.WithoutDatabase())
+	}
+	config, err := apiserver.LoadConfig(serverConfigPath, configProfile, loadOptions...)
 	if err != nil {
 		log.Fatal(err)
 	}
-	if err := apiserver.Start(config); err != nil {
+
+	command := flag.Arg(0)
+	if storeMode == storeMemory && command != "" && command != "serve" && command != "config" {
+		log.Fatal(errMemoryStoreServeOnly)
+	}
+
+	switch command {
+	case "", "serve":
+		if storeMode == storeMemory {
+			err = apiserver.StartInMemory(config, memoryOptions)
+		} else {
+			err = apiserver.Start(config)
+		}
+	case "migrate":
+		err = runMigrate(config, flag.Args()[1:])
+	case "user":
+		err = runUser(config, flag.Args()[1:])
+	case "session":
+		err = runSession(config, flag.Args()[1:])
+	case "config":
+		err = runConfig(config, flag.Args()[1:])
+	default:
+		flag.Usage()
+		err = fmt.Errorf("unknown command %q", command)
+	}
+	if err != nil {
 		log.Fatal(err)
 	}
 }
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"awesomeProject/internal/app/apiserver"
+	"awesomeProject/internal/app/migrator"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+)
+
+var errMigrateUsage = errors.New("usage: migrate up | down [N] | status | goto N")
+
+func runMigrate(config *apiserver.Config, args []string) error {
+	if len(args) == 0 {
+		return errMigrateUsage
+	}
+
+	db, err := apiserver.NewDatabaseConn(config, apiserver.NewLogger(config))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	schema, err := apiserver.NewMigrator(config, db)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	switch args[0] {
+	case "up":
+		return schema.Up(ctx)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return errMigrateUsage
+			}
+		}
+		return schema.Down(ctx, steps)
+	case "goto":
+		if len(args) < 2 {
+			return errMigrateUsage
+		}
+		version, err := strconv.ParseUint(args[1], 10, 32)
+		if err != nil {
+			return errMigrateUsage
+		}
+		return schema.Goto(ctx, uint(version))
+	case "status":
+		statuses, err := schema.Status(ctx)
+		if err != nil {
+			return err
+		}
+		printMigrationStatus(statuses)
+		return nil
+	default:
+		return errMigrateUsage
+	}
+}
+
+func printMigrationStatus(statuses []migrator.Status) {
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "VERSION\tNAME\tSTATE\tAPPLIED AT")
+	for _, status := range statuses {
+		state := "pending"
+		switch {
+		case status.Unknown:
+			state = "unknown"
+		case status.Modified:
+			state = "modified"
+		case status.Legacy:
+			state = "legacy"
+		case status.Applied:
+			state = "applied"
+		}
+		appliedAt := "-"
+		if status.Applied && !status.Legacy {
+			appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(writer, "%d\t%s\t%s\t%s\n", status.Version, status.Name, state, appliedAt)
+	}
+	writer.Flush()
+}
@@ -0,0 +1,2 @@
+ALTER TABLE users
+    ADD COLUMN session_version integer not null DEFAULT 0;
@@ -0,0 +1,2 @@
+ALTER TABLE users
+    ADD COLUMN version integer not null DEFAULT 1;
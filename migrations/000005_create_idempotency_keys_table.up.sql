@@ -0,0 +1,14 @@
+CREATE TABLE idempotency_keys
+(
+    scope        varchar     not null,
+    key          varchar     not null,
+    request_hash varchar     not null,
+    status       integer     not null DEFAULT 0,
+    headers      jsonb       not null DEFAULT '{}',
+    body         bytea,
+    created_at   timestamptz not null,
+    expires_at   timestamptz not null,
+    PRIMARY KEY (scope, key)
+);
+
+CREATE INDEX idempotency_keys_expires_at_idx ON idempotency_keys (expires_at);
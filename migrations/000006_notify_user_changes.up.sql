@@ -0,0 +1,13 @@
+CREATE FUNCTION notify_user_changed() RETURNS trigger AS
+$$
+BEGIN
+    PERFORM pg_notify('users_changed', OLD.id::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER users_notify_changed
+    AFTER UPDATE OR DELETE
+    ON users
+    FOR EACH ROW
+EXECUTE FUNCTION notify_user_changed();
@@ -0,0 +1,7 @@
+DROP INDEX users_username_key;
+
+ALTER TABLE users
+    DROP COLUMN display_name,
+    DROP COLUMN username,
+    DROP COLUMN locale,
+    DROP COLUMN timezone;
@@ -0,0 +1,7 @@
+ALTER TABLE users
+    ADD COLUMN display_name text not null DEFAULT '',
+    ADD COLUMN username     text not null DEFAULT '',
+    ADD COLUMN locale       text not null DEFAULT 'en',
+    ADD COLUMN timezone     text not null DEFAULT 'UTC';
+
+CREATE UNIQUE INDEX users_username_key ON users (username) WHERE username <> '';
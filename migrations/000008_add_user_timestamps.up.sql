@@ -0,0 +1,4 @@
+ALTER TABLE users
+    ADD COLUMN created_at    timestamptz not null DEFAULT now(),
+    ADD COLUMN updated_at    timestamptz not null DEFAULT now(),
+    ADD COLUMN last_login_at timestamptz;
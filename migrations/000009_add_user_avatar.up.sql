@@ -0,0 +1,2 @@
+ALTER TABLE users
+    ADD COLUMN avatar_version text not null DEFAULT '';
@@ -0,0 +1,8 @@
+package migrations
+
+import "embed"
+
+// FS holds the SQL migrations applied to the postgres database.
+//
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"awesomeProject/internal/app/apiserver"
+	"awesomeProject/internal/app/model"
+	"context"
+	"errors"
+	"flag"
+)
+
+var errSessionUsage = errors.New("usage: session revoke -id N | -email EMAIL")
+
+func runSession(config *apiserver.Config, args []string) error {
+	if len(args) == 0 || args[0] != "revoke" {
+		return errSessionUsage
+	}
+
+	s, closeStore, err := openStore(config)
+	if err != nil {
+		return err
+	}
+	defer closeStore()
+	repository := s.UserRepository()
+	ctx := context.Background()
+
+	flags := flag.NewFlagSet("session revoke", flag.ContinueOnError)
+	selector := newUserSelector(flags)
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+	user, err := selector.find(ctx, repository)
+	if err != nil {
+		return err
+	}
+
+	// Bumping the version revokes every cookie of the user at once.
+	user.SessionVersion++
+	if err := repository.Update(ctx, user); err != nil {
+		return err
+	}
+	return printUsers([]*model.User{user}, "")
+}
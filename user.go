@@ -0,0 +1,193 @@
+package main
+
+import (
+	"awesomeProject/internal/app/apiserver"
+	"awesomeProject/internal/app/model"
+	"awesomeProject/internal/app/store"
+	"context"
+	"errors"
+	"flag"
+	"strconv"
+	"time"
+)
+
+var (
+	errUserUsage    = errors.New("usage: user create | list | set-role | reset-password | delete")
+	errSetRoleUsage = errors.New("usage: user set-role -id N | -email E -role basic | moderator | admin")
+)
+
+type userOutput struct {
+	Id        int       `json:"id"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	Username  string    `json:"username,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	Password  string    `json:"password,omitempty"`
+}
+
+func runUser(config *apiserver.Config, args []string) error {
+	if len(args) == 0 {
+		return errUserUsage
+	}
+
+	s, closeStore, err := openStore(config)
+	if err != nil {
+		return err
+	}
+	defer closeStore()
+	repository := s.UserRepository()
+	ctx := context.Background()
+
+	command, flags := args[0], flag.NewFlagSet("user "+args[0], flag.ContinueOnError)
+	switch command {
+	case "create":
+		email := flags.String("email", "", "Email of the new user")
+		password := flags.String("password", "", "Password of the new user, generated when empty")
+		role := flags.String("role", model.RoleBasic, "Role of the new user")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		generated := *password == ""
+		if generated {
+			if *password, err = generatePassword(); err != nil {
+				return err
+			}
+		}
+		user := &model.User{
+			Email:    *email,
+			Password: &model.Password{Original: *password},
+			Role:     *role,
+		}
+		if err := repository.Create(ctx, user); err != nil {
+			return err
+		}
+		if !generated {
+			*password = ""
+		}
+		return printUsers([]*model.User{user}, *password)
+	case "list":
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		users, err := repository.AllUsers(ctx)
+		if err != nil {
+			return err
+		}
+		return printUsers(users, "")
+	case "set-role":
+		selector := newUserSelector(flags)
+		role := flags.String("role", "", "New role of the user, required")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		// An empty role would become basic on update and silently demote the user.
+		switch *role {
+		case model.RoleBasic, model.RoleModerator, model.RoleAdmin:
+		default:
+			return errSetRoleUsage
+		}
+		user, err := selector.find(ctx, repository)
+		if err != nil {
+			return err
+		}
+		user.Role = *role
+		if err := repository.Update(ctx, user); err != nil {
+			return err
+		}
+		return printUsers([]*model.User{user}, "")
+	case "reset-password":
+		selector := newUserSelector(flags)
+		password := flags.String("password", "", "New password, generated when empty")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		user, err := selector.find(ctx, repository)
+		if err != nil {
+			return err
+		}
+		generated := *password == ""
+		if generated {
+			if *password, err = generatePassword(); err != nil {
+				return err
+			}
+		}
+		user.Password = &model.Password{Original: *password}
+		user.SessionVersion++
+		if err := repository.Update(ctx, user); err != nil {
+			return err
+		}
+		if !generated {
+			*password = ""
+		}
+		return printUsers([]*model.User{user}, *password)
+	case "delete":
+		selector := newUserSelector(flags)
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		user, err := selector.find(ctx, repository)
+		if err != nil {
+			return err
+		}
+		if err := repository.Delete(ctx, user); err != nil {
+			return err
+		}
+		return printUsers([]*model.User{user}, "")
+	default:
+		return errUserUsage
+	}
+}
+
+// userSelector finds the user an administrative command operates on by id or email.
+type userSelector struct {
+	id    *int
+	email *string
+}
+
+func newUserSelector(flags *flag.FlagSet) *userSelector {
+	return &userSelector{
+		id:    flags.Int("id", 0, "Id of the user"),
+		email: flags.String("email", "", "Email of the user"),
+	}
+}
+
+func (s *userSelector) find(ctx context.Context, repository store.UserRepository) (*model.User, error) {
+	switch {
+	case *s.id != 0 && *s.email != "":
+		return nil, errors.New("either -id or -email must be given, not both")
+	case *s.id != 0:
+		return repository.FindById(ctx, *s.id)
+	case *s.email != "":
+		return repository.FindByEmail(ctx, *s.email)
+	default:
+		return nil, errors.New("-id or -email is required")
+	}
+}
+
+// printUsers prints users, password is shown only when it was generated by the command.
+func printUsers(users []*model.User, password string) error {
+	outputs := make([]userOutput, 0, len(users))
+	rows := make([][]string, 0, len(users))
+	for _, user := range users {
+		output := userOutput{
+			Id:        user.Id,
+			Email:     user.Email,
+			Role:      user.Role,
+			Username:  user.Username,
+			CreatedAt: user.CreatedAt,
+			Password:  password,
+		}
+		outputs = append(outputs, output)
+		row := []string{strconv.Itoa(output.Id), output.Email, output.Role, output.Username, output.CreatedAt.Format(time.RFC3339)}
+		if password != "" {
+			row = append(row, password)
+		}
+		rows = append(rows, row)
+	}
+
+	header := []string{"ID", "EMAIL", "ROLE", "USERNAME", "CREATED"}
+	if password != "" {
+		header = append(header, "GENERATED PASSWORD")
+	}
+	return printResult(outputs, header, rows)
+}